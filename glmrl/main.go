@@ -8,8 +8,10 @@ import (
 	"github.com/Semior001/glmrl/pkg/git/engine"
 	"github.com/Semior001/glmrl/pkg/misc"
 	"github.com/Semior001/glmrl/pkg/service"
+	"github.com/Semior001/glmrl/pkg/tui"
 	"github.com/hashicorp/logutils"
 	"github.com/jessevdk/go-flags"
+	"github.com/samber/lo"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/jaeger"
 	"go.opentelemetry.io/otel/sdk/resource"
@@ -18,26 +20,64 @@ import (
 	"gopkg.in/yaml.v3"
 	"io"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime/debug"
+	"strings"
+	"time"
 )
 
 type options struct {
 	Config string `short:"c" long:"config" description:"path to config file" default:"~/.glmrl/config.yaml"`
+	Engine string `yaml:"engine" long:"engine" env:"ENGINE" choice:"gitlab" choice:"github" choice:"gitea" default:"gitlab" description:"git engine to use"`
 	Gitlab struct {
 		BaseURL string `yaml:"base_url" long:"base-url" env:"BASE_URL" description:"gitlab host"`
 		Token   string `yaml:"token" long:"token" env:"TOKEN" description:"gitlab token with read_api scope"`
 	} `yaml:"gitlab" group:"gitlab" namespace:"gitlab" env-namespace:"GITLAB"`
-	List  cmd.List `yaml:"-" command:"list" description:"list pull requests"`
-	Debug bool     `long:"dbg" env:"DEBUG" description:"turn on debug mode"`
-	Trace struct {
+	Github struct {
+		BaseURL string   `yaml:"base_url" long:"base-url" env:"BASE_URL" description:"github api url (default: https://api.github.com)"`
+		Token   string   `yaml:"token" long:"token" env:"TOKEN" description:"github token with repo scope"`
+		Owners  []string `yaml:"owners" long:"owner" env:"OWNERS" env-delim:"," description:"users or organizations to list pull requests from, if none, lists the ones that involve me"`
+	} `yaml:"github" group:"github" namespace:"github" env-namespace:"GITHUB"`
+	Gitea struct {
+		BaseURL string `yaml:"base_url" long:"base-url" env:"BASE_URL" description:"gitea or forgejo host"`
+		Token   string `yaml:"token" long:"token" env:"TOKEN" description:"gitea token with read:repository and read:user scopes"`
+	} `yaml:"gitea" group:"gitea" namespace:"gitea" env-namespace:"GITEA"`
+	Sizes struct {
+		S int `yaml:"s" long:"s" env:"S" default:"50" description:"max changed lines of a small merge request"`
+		M int `yaml:"m" long:"m" env:"M" default:"200" description:"max changed lines of a medium merge request"`
+		L int `yaml:"l" long:"l" env:"L" default:"800" description:"max changed lines of a large merge request, bigger ones are XL"`
+	} `yaml:"sizes" group:"sizes" namespace:"sizes" env-namespace:"SIZES"`
+	Limits struct {
+		Concurrency int           `yaml:"concurrency" long:"concurrency" env:"CONCURRENCY" default:"8" description:"max number of concurrent requests to each instance, 0 means no limit"`
+		Retries     int           `yaml:"retries" long:"retries" env:"RETRIES" default:"5" description:"max number of retries of throttled or failed requests"`
+		Backoff     time.Duration `yaml:"backoff" long:"backoff" env:"BACKOFF" default:"1s" description:"delay before the first retry, doubled on each next one, unless the server asks for another one"`
+	} `yaml:"limits" group:"limits" namespace:"limits" env-namespace:"LIMITS"`
+	Cache struct {
+		Dir      string `yaml:"dir" long:"dir" env:"DIR" default:"~/.glmrl/cache" description:"directory to keep the details of pull requests in"`
+		Disabled bool   `yaml:"disabled" long:"disabled" env:"DISABLED" description:"load everything from instances, bypassing the cache"`
+		Clear    bool   `yaml:"-" long:"clear" description:"clear the cache before loading"`
+	} `yaml:"cache" group:"cache" namespace:"cache" env-namespace:"CACHE"`
+	Instances map[string]instance  `yaml:"instances"`
+	Queries   map[string]yaml.Node `yaml:"queries"`
+	List      cmd.List             `yaml:"-" command:"list" description:"list pull requests"`
+	Debug     bool                 `long:"dbg" env:"DEBUG" description:"turn on debug mode"`
+	Trace     struct {
 		Enabled bool   `long:"enabled" env:"ENABLED" description:"enable tracing"`
 		Host    string `long:"host" env:"HOST" description:"jaeger agent host"`
 		Port    string `long:"port" env:"PORT" description:"jaeger agent port"`
 	} `yaml:"-" group:"trace" namespace:"trace" env-namespace:"TRACE"`
 }
 
+// instance describes a named instance of a git engine, declared in the config.
+type instance struct {
+	Engine  string   `yaml:"engine"`
+	BaseURL string   `yaml:"base_url"`
+	Token   string   `yaml:"token"`
+	Owners  []string `yaml:"owners"` // github only
+}
+
 var version = "unknown"
 
 func getVersion() string {
@@ -49,7 +89,8 @@ func getVersion() string {
 }
 
 func main() {
-	fmt.Printf("glmrl version: %s\n", getVersion())
+	// version goes to stderr to not mess up the output of non-interactive commands
+	fmt.Fprintf(os.Stderr, "glmrl version: %s\n", getVersion())
 
 	opts := options{}
 
@@ -60,6 +101,12 @@ func main() {
 
 		opts = loadConfig(opts.Config, opts)
 
+		if len(opts.List.Query) > 0 {
+			if err := applyQuery(p, &opts); err != nil {
+				return fmt.Errorf("apply saved query: %w", err)
+			}
+		}
+
 		copts, err := initCommon(opts)
 		if err != nil {
 			return fmt.Errorf("init common options: %w", err)
@@ -88,9 +135,7 @@ func loadConfig(path string, opts options) options {
 		return opts
 	}
 
-	if path[:2] == "~/" {
-		path = filepath.Join(os.Getenv("HOME"), path[2:])
-	}
+	path = expandHome(path)
 
 	file, err := os.Open(path)
 	if err != nil {
@@ -103,38 +148,168 @@ func loadConfig(path string, opts options) options {
 	}
 	defer file.Close()
 
+	// sections are filled with the values from flags and env, so that the config
+	// overrides only the fields, that are set in it, including zero ones
 	var cfg options
+	cfg.Gitlab, cfg.Github, cfg.Gitea, cfg.Limits = opts.Gitlab, opts.Github, opts.Gitea, opts.Limits
 	if err = yaml.NewDecoder(file).Decode(&cfg); err != nil {
 		log.Printf("[WARN] failed to decode config at %s: %v", path, err)
 		return opts
 	}
 
-	opts.Gitlab = cfg.Gitlab
+	if cfg.Engine != "" {
+		opts.Engine = cfg.Engine
+	}
+
+	opts.Gitlab, opts.Github, opts.Gitea, opts.Limits = cfg.Gitlab, cfg.Github, cfg.Gitea, cfg.Limits
+	opts.Instances = cfg.Instances
+	opts.Queries = cfg.Queries
+
+	// thresholds have defaults, so only the ones from the config override them
+	opts.Sizes.S = lo.Ternary(cfg.Sizes.S != 0, cfg.Sizes.S, opts.Sizes.S)
+	opts.Sizes.M = lo.Ternary(cfg.Sizes.M != 0, cfg.Sizes.M, opts.Sizes.M)
+	opts.Sizes.L = lo.Ternary(cfg.Sizes.L != 0, cfg.Sizes.L, opts.Sizes.L)
+
+	opts.Cache.Dir = lo.Ternary(cfg.Cache.Dir != "", cfg.Cache.Dir, opts.Cache.Dir)
+	opts.Cache.Disabled = opts.Cache.Disabled || cfg.Cache.Disabled
 	return opts
 }
 
+// applyQuery applies the saved queries from the config to the list command options.
+func applyQuery(p *flags.Parser, opts *options) error {
+	listCmd := p.Find("list")
+	return opts.List.ApplyQueries(opts.Queries, func(longName string) bool {
+		opt := listCmd.FindOptionByLongName(longName)
+		return opt != nil && opt.IsSet() && !opt.IsSetDefault()
+	})
+}
+
 func initCommon(opts options) (cmd.CommonOpts, error) {
-	if opts.Gitlab.Token == "" && opts.Gitlab.BaseURL == "" {
-		return cmd.CommonOpts{}, errors.New("gitlab creds not provided")
+	instances, err := collectInstances(opts)
+	if err != nil {
+		return cmd.CommonOpts{}, fmt.Errorf("collect instances: %w", err)
+	}
+
+	limits := engine.Limits{
+		Concurrency: opts.Limits.Concurrency,
+		Retries:     opts.Limits.Retries,
+		Backoff:     opts.Limits.Backoff,
 	}
 
 	c := cmd.CommonOpts{
 		Version: getVersion(),
+		Sizes:   tui.SizeThresholds{S: opts.Sizes.S, M: opts.Sizes.M, L: opts.Sizes.L},
 		PrepareService: func(ctx context.Context) (*service.Service, error) {
-			gl, err := engine.NewGitlab(opts.Gitlab.Token, opts.Gitlab.BaseURL, getVersion())
-			if err != nil {
-				return nil, fmt.Errorf("init gitlab client: %w", err)
+			engines := make(map[string]engine.Interface, len(instances))
+			for name, inst := range instances {
+				eng, err := newEngine(inst, limits)
+				if err != nil {
+					return nil, fmt.Errorf("init engine for instance %q: %w", name, err)
+				}
+
+				if eng, err = withCache(eng, name, opts); err != nil {
+					return nil, fmt.Errorf("init cache for instance %q: %w", name, err)
+				}
+
+				engines[name] = eng
 			}
 
-			eng := engine.NewInterfaceWithTracing(gl, "Gitlab", misc.AttributesSpanDecorator)
-
-			return service.NewService(ctx, eng)
+			return service.NewService(ctx, engines)
 		},
 	}
 
 	return c, nil
 }
 
+// collectInstances returns instances declared in the config, or, if there are none,
+// the single instance of the selected engine, named after the engine.
+func collectInstances(opts options) (map[string]instance, error) {
+	instances := opts.Instances
+	if len(instances) == 0 {
+		inst := instance{Engine: opts.Engine}
+		switch opts.Engine {
+		case "github":
+			inst.BaseURL, inst.Token, inst.Owners = opts.Github.BaseURL, opts.Github.Token, opts.Github.Owners
+		case "gitea":
+			inst.BaseURL, inst.Token = opts.Gitea.BaseURL, opts.Gitea.Token
+		default:
+			inst.BaseURL, inst.Token = opts.Gitlab.BaseURL, opts.Gitlab.Token
+		}
+		instances = map[string]instance{opts.Engine: inst}
+	}
+
+	for name, inst := range instances {
+		switch inst.Engine {
+		case "github":
+			if inst.Token == "" {
+				return nil, fmt.Errorf("github creds not provided for %q", name)
+			}
+		case "gitea":
+			if inst.Token == "" || inst.BaseURL == "" {
+				return nil, fmt.Errorf("gitea creds not provided for %q", name)
+			}
+		case "gitlab", "":
+			if inst.Token == "" && inst.BaseURL == "" {
+				return nil, fmt.Errorf("gitlab creds not provided for %q", name)
+			}
+		default:
+			return nil, fmt.Errorf("unknown engine %q for %q", inst.Engine, name)
+		}
+	}
+
+	return instances, nil
+}
+
+func newEngine(inst instance, limits engine.Limits) (engine.Interface, error) {
+	switch inst.Engine {
+	case "github":
+		gh, err := engine.NewGithub(inst.Token, inst.BaseURL, getVersion(), inst.Owners, limits)
+		if err != nil {
+			return nil, fmt.Errorf("init github client: %w", err)
+		}
+
+		return engine.NewInterfaceWithTracing(gh, "Github", misc.AttributesSpanDecorator), nil
+	case "gitea":
+		gt, err := engine.NewGitea(inst.Token, inst.BaseURL, getVersion(), limits)
+		if err != nil {
+			return nil, fmt.Errorf("init gitea client: %w", err)
+		}
+
+		return engine.NewInterfaceWithTracing(gt, "Gitea", misc.AttributesSpanDecorator), nil
+	default:
+		gl, err := engine.NewGitlab(inst.Token, inst.BaseURL, getVersion(), limits)
+		if err != nil {
+			return nil, fmt.Errorf("init gitlab client: %w", err)
+		}
+
+		return engine.NewInterfaceWithTracing(gl, "Gitlab", misc.AttributesSpanDecorator), nil
+	}
+}
+
+// withCache wraps the engine with the disk cache, unless it is disabled.
+func withCache(eng engine.Interface, name string, opts options) (engine.Interface, error) {
+	cache := engine.NewDiskCache(eng, filepath.Join(expandHome(opts.Cache.Dir), url.PathEscape(name)))
+	if opts.Cache.Clear {
+		if err := cache.Clear(); err != nil {
+			return nil, fmt.Errorf("clear cache: %w", err)
+		}
+	}
+
+	if opts.Cache.Disabled {
+		return eng, nil
+	}
+
+	return engine.NewInterfaceWithTracing(cache, "DiskCache", misc.AttributesSpanDecorator), nil
+}
+
+// expandHome replaces the leading "~/" in the path with the home directory.
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(os.Getenv("HOME"), path[2:])
+	}
+	return path
+}
+
 func setupLog(dbg bool) {
 	filter := &logutils.LevelFilter{
 		Levels:   []logutils.LogLevel{"DEBUG", "INFO", "WARN", "ERROR"},
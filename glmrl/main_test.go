@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	var opts options
+	opts.Engine = "gitlab"
+	opts.Gitlab.BaseURL, opts.Gitlab.Token = "https://gitlab.example.com", "flag-token"
+	opts.Github.Token = "env-token"
+	opts.Gitea.BaseURL = "https://gitea.example.com"
+	opts.Limits.Concurrency, opts.Limits.Retries, opts.Limits.Backoff = 8, 5, time.Second
+
+	tests := []struct {
+		name string
+		cfg  string
+		want func() options
+	}{
+		{
+			name: "sections, missing in the config, keep flags and env",
+			cfg:  "engine: github\n",
+			want: func() options {
+				want := opts
+				want.Engine = "github"
+				return want
+			},
+		},
+		{
+			name: "config overrides only the fields, set in it",
+			cfg:  "gitlab:\n  token: cfg-token\ngithub:\n  owners: [org]\n",
+			want: func() options {
+				want := opts
+				want.Gitlab.Token = "cfg-token"
+				want.Github.Owners = []string{"org"}
+				return want
+			},
+		},
+		{
+			name: "zero limits from the config",
+			cfg:  "limits:\n  concurrency: 0\n  retries: 0\n",
+			want: func() options {
+				want := opts
+				want.Limits.Concurrency, want.Limits.Retries = 0, 0
+				return want
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			assert.NoError(t, os.WriteFile(path, []byte(tt.cfg), 0o600))
+			assert.Equal(t, tt.want(), loadConfig(path, opts))
+		})
+	}
+}
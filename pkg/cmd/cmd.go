@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"github.com/Semior001/glmrl/pkg/service"
+	"github.com/Semior001/glmrl/pkg/tui"
 	"github.com/samber/lo"
 )
 
@@ -10,17 +11,19 @@ import (
 type CommonOpts struct {
 	PrepareService func(ctx context.Context) (*service.Service, error)
 	Version        string
+	Sizes          tui.SizeThresholds
 }
 
 func (c *CommonOpts) Set(opts CommonOpts) {
 	c.PrepareService = opts.PrepareService
 	c.Version = opts.Version
+	c.Sizes = opts.Sizes
 }
 
 // FilterGroup is a group of include/exclude filters
 type FilterGroup struct {
-	Include []string `long:"include" description:"list only entries that include the given value"`
-	Exclude []string `long:"exclude" description:"list only entries that exclude the given value"`
+	Include []string `long:"include" yaml:"include" description:"list only entries that include the given value"`
+	Exclude []string `long:"exclude" yaml:"exclude" description:"list only entries that exclude the given value"`
 }
 
 // Empty returns true if the filter group is empty.
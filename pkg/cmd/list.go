@@ -10,29 +10,58 @@ import (
 	"github.com/Semior001/glmrl/pkg/tui"
 	"github.com/Semior001/glmrl/pkg/tui/teax"
 	"github.com/samber/lo"
+	"os"
 	"time"
 )
 
 // List lists all merge requests that satisfy the given criteria.
 type List struct {
-	CommonOpts
-	State                      git.State    `long:"state" description:"list only merge requests with the given state"`
-	Labels                     FilterGroup  `group:"labels" namespace:"labels" env-namespace:"LABELS"`
-	Authors                    FilterGroup  `group:"authors" namespace:"authors" env-namespace:"AUTHORS"`
-	ProjectPaths               FilterGroup  `group:"project-paths" namespace:"project-paths" env-namespace:"PROJECT_PATHS"`
-	ApprovedByMe               NillableBool `long:"approved-by-me" choice:"true" choice:"false" description:"list only merge requests approved by me"`
-	WithoutMyUnresolvedThreads bool         `long:"without-my-unresolved-threads" description:"list only merge requests without MY unresolved threads, but lists threads where my action is required"`
-	NotEnoughApprovals         NillableBool `long:"not-enough-approvals" choice:"true" choice:"false" description:"list only merge requests with not enough approvals, but show the ones where I've been requested as a reviewer and didn't approve it"`
+	CommonOpts                 `yaml:"-"`
+	Query                      []string           `long:"query" yaml:"-" description:"use the saved query from the config, flags override its values, repeat to show each query in its own tab"`
+	State                      git.State          `long:"state" yaml:"state" description:"list only merge requests with the given state"`
+	Labels                     FilterGroup        `group:"labels" namespace:"labels" env-namespace:"LABELS" yaml:"labels"`
+	Authors                    FilterGroup        `group:"authors" namespace:"authors" env-namespace:"AUTHORS" yaml:"authors"`
+	ProjectPaths               FilterGroup        `group:"project-paths" namespace:"project-paths" env-namespace:"PROJECT_PATHS" yaml:"project-paths"`
+	ApprovedByMe               NillableBool       `long:"approved-by-me" choice:"true" choice:"false" yaml:"approved-by-me" description:"list only merge requests approved by me"`
+	WithoutMyUnresolvedThreads bool               `long:"without-my-unresolved-threads" yaml:"without-my-unresolved-threads" description:"list only merge requests without MY unresolved threads, but lists threads where my action is required"`
+	ApprovedSinceMyLastPush    bool               `long:"approved-since-my-last-push" yaml:"approved-since-my-last-push" description:"list only merge requests, approved by someone after my last push to them"`
+	NewCommitsSinceMyReview    bool               `long:"new-commits-since-my-review" yaml:"new-commits-since-my-review" description:"list only merge requests with new commits after my last approval or comment"`
+	NotEnoughApprovals         NillableBool       `long:"not-enough-approvals" choice:"true" choice:"false" yaml:"not-enough-approvals" description:"list only merge requests with not enough approvals, but show the ones where I've been requested as a reviewer and didn't approve it"`
+	Pipeline                   git.PipelineStatus `long:"pipeline" choice:"success" choice:"failed" choice:"running" yaml:"pipeline" description:"list only merge requests with the given status of the latest pipeline"`
+	RebaseRequired             NillableBool       `long:"rebase-required" choice:"true" choice:"false" yaml:"rebase-required" description:"list only merge requests, which source branch has conflicts or is behind the target one"`
+	Reviewer                   string             `long:"reviewer" yaml:"reviewer" description:"list only merge requests, where the given user is requested as a reviewer"`
+	Assignee                   string             `long:"assignee" yaml:"assignee" description:"list only merge requests, assigned to the given user"`
+	Group                      string             `long:"group" yaml:"group" description:"list only merge requests of projects in the given group (or of the given owner)"`
+	Search                     string             `long:"search" yaml:"search" description:"list only merge requests with the given text in the title or the description"`
+	TargetBranch               string             `long:"target-branch" yaml:"target-branch" description:"list only merge requests to the given branch"`
+	Where                      string             `long:"where" yaml:"where" description:"list only merge requests that satisfy the expression, e.g. 'approvals.by contains \"alice\" && age > 48h && !draft'"`
 	Sort                       struct {
-		By    string         `long:"by" choice:"created" choice:"updated" choice:"title" default:"created" description:"sort by the given field"`
-		Order misc.SortOrder `long:"order" choice:"asc" choice:"desc" default:"desc" description:"sort in the given order"`
-	} `group:"sort" namespace:"sort" env-namespace:"SORT"`
+		By    string         `long:"by" choice:"created" choice:"updated" choice:"title" default:"created" yaml:"by" description:"sort by the given field"`
+		Order misc.SortOrder `long:"order" choice:"asc" choice:"desc" default:"desc" yaml:"order" description:"sort in the given order"`
+	} `group:"sort" namespace:"sort" env-namespace:"SORT" yaml:"sort"`
+	CreatedWithin time.Duration `long:"created-within" yaml:"created-within" description:"list only merge requests created within the given duration"`
+	UpdatedWithin time.Duration `long:"updated-within" yaml:"updated-within" description:"list only merge requests updated within the given duration"`
+	StaleFor      time.Duration `long:"stale-for" yaml:"stale-for" description:"list only merge requests without any activity of users for at least the given duration"`
+	Size          struct {
+		MaxLines int `long:"max-lines" yaml:"max-lines" description:"list only merge requests with at most the given number of changed lines"`
+		MaxFiles int `long:"max-files" yaml:"max-files" description:"list only merge requests with at most the given number of changed files"`
+	} `group:"size" namespace:"size" env-namespace:"SIZE" yaml:"size"`
 	Pagination struct {
-		Page    int `long:"page" description:"page number"`
-		PerPage int `long:"per-page" description:"number of items per page"`
-	} `group:"pagination" namespace:"pagination" env-namespace:"PAGINATION" description:"pagination options, provide none to list all"`
-	Action       string        `long:"action" choice:"open" choice:"copy" default:"open" description:"action to perform on pressing enter"`
-	PollInterval time.Duration `long:"poll-interval" default:"5m" description:"interval to poll for new merge requests, 0 means no polling, only manual refresh"`
+		Page    int `long:"page" yaml:"page" description:"page number"`
+		PerPage int `long:"per-page" yaml:"per-page" description:"number of items per page"`
+	} `group:"pagination" namespace:"pagination" env-namespace:"PAGINATION" yaml:"pagination" description:"pagination options, provide none to list all"`
+	Action       string        `long:"action" choice:"open" choice:"copy" default:"open" yaml:"action" description:"action to perform on pressing enter"`
+	PollInterval time.Duration `long:"poll-interval" default:"5m" yaml:"poll-interval" description:"interval to poll for new merge requests, 0 means no polling, only manual refresh"`
+	Output       OutputFormat  `long:"output" short:"o" choice:"table" choice:"json" choice:"jsonl" choice:"csv" choice:"markdown" choice:"template" default:"table" yaml:"output" description:"output format, all except table print the list to stdout and exit"`
+	Template     string        `long:"template" yaml:"template" description:"go template to print each merge request with, used with --output=template"`
+
+	tabs []listTab // set if several saved queries are requested
+}
+
+// listTab is a set of options for a single tab, made of a saved query.
+type listTab struct {
+	name string
+	opts List
 }
 
 func (c List) validateBackendFilters() error {
@@ -41,10 +70,19 @@ func (c List) validateBackendFilters() error {
 		present bool
 	}
 
+	// authors and projects are pushed down to engines only if there is a single one
 	filters := []filter{
 		{name: "state", present: c.State != ""},
 		{name: "labels", present: !c.Labels.Empty()},
-		{name: "authors", present: !c.Authors.Empty()},
+		{name: "single author", present: len(c.Authors.Include) == 1},
+		{name: "single project path", present: len(c.ProjectPaths.Include) == 1},
+		{name: "reviewer", present: c.Reviewer != ""},
+		{name: "assignee", present: c.Assignee != ""},
+		{name: "group", present: c.Group != ""},
+		{name: "search", present: c.Search != ""},
+		{name: "target-branch", present: c.TargetBranch != ""},
+		{name: "created-within", present: c.CreatedWithin > 0},
+		{name: "updated-within", present: c.UpdatedWithin > 0},
 		{name: "pagination", present: c.Pagination.Page != 0 && c.Pagination.PerPage != 0},
 	}
 
@@ -62,25 +100,37 @@ func (c List) validateBackendFilters() error {
 func (c List) Execute([]string) error {
 	ctx := context.Background()
 
-	req := service.ListPRsRequest{
-		ListPRsRequest: engine.ListPRsRequest{
-			State:  c.State,
-			Labels: misc.Filter[string]{Include: c.Labels.Include, Exclude: c.Labels.Exclude},
-			Sort: misc.Sort{
-				By:    transformSortBy(c.Sort.By),
-				Order: c.Sort.Order,
-			},
-			Pagination: misc.Pagination{Page: c.Pagination.Page, PerPage: c.Pagination.PerPage},
-		},
-		ApprovedByMe:               c.ApprovedByMe.Value(),
-		WithoutMyUnresolvedThreads: c.WithoutMyUnresolvedThreads,
-		SatisfiesApprovalRules:     Not(c.NotEnoughApprovals).Value(),
-		Authors:                    misc.Filter[string]{Include: c.Authors.Include, Exclude: c.Authors.Exclude},
-		ProjectPaths:               misc.Filter[string]{Include: c.ProjectPaths.Include, Exclude: c.ProjectPaths.Exclude},
+	var (
+		req  service.ListPRsRequest
+		tabs []tui.ListPRTab
+		err  error
+	)
+
+	// each saved query has its own filters, so the root ones
+	// are used and validated only if there are no queries
+	for _, tab := range c.tabs {
+		treq, err := tab.opts.request()
+		if err != nil {
+			return fmt.Errorf("query %q: %w", tab.name, err)
+		}
+		tabs = append(tabs, tui.ListPRTab{Name: tab.name, Request: treq, PollInterval: tab.opts.PollInterval})
 	}
 
-	if err := c.validateBackendFilters(); err != nil {
-		return fmt.Errorf("validate backend filters: %w", err)
+	if len(tabs) == 0 {
+		if req, err = c.request(); err != nil {
+			return err
+		}
+	}
+
+	var w *prWriter
+	if c.Output != OutputTable {
+		if len(tabs) > 0 {
+			return fmt.Errorf("several queries can be shown only in the %s output", OutputTable)
+		}
+
+		if w, err = newPRWriter(c.Output, c.Template); err != nil {
+			return fmt.Errorf("prepare %s output: %w", c.Output, err)
+		}
 	}
 
 	svc, err := c.PrepareService(ctx)
@@ -88,12 +138,31 @@ func (c List) Execute([]string) error {
 		return fmt.Errorf("init service: %w", err)
 	}
 
+	tsvc := service.NewtracingServiceWithTracing(svc, "PrepareService", misc.AttributesSpanDecorator)
+
+	if w != nil {
+		req.Details = w.Details()
+		prs, err := tsvc.ListPullRequests(ctx, req)
+		if err != nil {
+			return fmt.Errorf("list merge requests: %w", err)
+		}
+
+		if err = w.Write(os.Stdout, prs); err != nil {
+			return fmt.Errorf("write merge requests: %w", err)
+		}
+
+		return nil
+	}
+
 	tbl, err := tui.NewListPR(ctx, tui.ListPRParams{
-		Service:      service.NewtracingServiceWithTracing(svc, "PrepareService", misc.AttributesSpanDecorator),
+		Service:      tsvc,
 		Request:      req,
+		Tabs:         tabs,
 		OpenOnEnter:  c.Action == "open",
 		PollInterval: c.PollInterval,
 		Version:      c.Version,
+		ShowInstance: len(svc.Instances()) > 1,
+		Sizes:        c.Sizes,
 	})
 	if err != nil {
 		return fmt.Errorf("initialize list prs tui: %w", err)
@@ -106,6 +175,54 @@ func (c List) Execute([]string) error {
 	return nil
 }
 
+// request builds the request to the service from the options.
+func (c List) request() (service.ListPRsRequest, error) {
+	req := service.ListPRsRequest{
+		ListPRsRequest: engine.ListPRsRequest{
+			State:  c.State,
+			Labels: misc.Filter[string]{Include: c.Labels.Include, Exclude: c.Labels.Exclude},
+			Sort: misc.Sort{
+				By:    transformSortBy(c.Sort.By),
+				Order: c.Sort.Order,
+			},
+			Pagination: misc.Pagination{Page: c.Pagination.Page, PerPage: c.Pagination.PerPage},
+		},
+		ApprovedByMe:               c.ApprovedByMe.Value(),
+		WithoutMyUnresolvedThreads: c.WithoutMyUnresolvedThreads,
+		ApprovedSinceMyLastPush:    c.ApprovedSinceMyLastPush,
+		NewCommitsSinceMyReview:    c.NewCommitsSinceMyReview,
+		Pipeline:                   c.Pipeline,
+		RebaseRequired:             c.RebaseRequired.Value(),
+		MaxLines:                   c.Size.MaxLines,
+		MaxFiles:                   c.Size.MaxFiles,
+		UpdatedWithin:              c.UpdatedWithin,
+		CreatedWithin:              c.CreatedWithin,
+		StaleFor:                   c.StaleFor,
+		Reviewer:                   c.Reviewer,
+		Assignee:                   c.Assignee,
+		Group:                      c.Group,
+		Search:                     c.Search,
+		TargetBranch:               c.TargetBranch,
+		SatisfiesApprovalRules:     Not(c.NotEnoughApprovals).Value(),
+		Authors:                    misc.Filter[string]{Include: c.Authors.Include, Exclude: c.Authors.Exclude},
+		ProjectPaths:               misc.Filter[string]{Include: c.ProjectPaths.Include, Exclude: c.ProjectPaths.Exclude},
+	}
+
+	if err := c.validateBackendFilters(); err != nil {
+		return service.ListPRsRequest{}, fmt.Errorf("validate backend filters: %w", err)
+	}
+
+	if c.Where != "" {
+		var err error
+		if req.Where, err = service.ParseWhere(c.Where); err != nil {
+			return service.ListPRsRequest{}, fmt.Errorf("parse where expression, available fields:\n%s\n%w",
+				service.WhereHelp(), err)
+		}
+	}
+
+	return req, nil
+}
+
 func transformSortBy(by string) misc.SortBy {
 	switch by {
 	case "created":
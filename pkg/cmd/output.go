@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/Semior001/glmrl/pkg/git"
+	"github.com/Semior001/glmrl/pkg/git/engine"
+	"github.com/samber/lo"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// OutputFormat is a format to print the list of pull requests in.
+type OutputFormat string
+
+const (
+	// OutputTable shows pull requests in the interactive table.
+	OutputTable OutputFormat = "table"
+	// OutputJSON prints pull requests as a JSON array.
+	OutputJSON OutputFormat = "json"
+	// OutputJSONL prints each pull request as a JSON object on a separate line.
+	OutputJSONL OutputFormat = "jsonl"
+	// OutputCSV prints pull requests as CSV with a header.
+	OutputCSV OutputFormat = "csv"
+	// OutputMarkdown prints pull requests as a markdown table.
+	OutputMarkdown OutputFormat = "markdown"
+	// OutputTemplate prints each pull request with the user-provided go template.
+	OutputTemplate OutputFormat = "template"
+)
+
+// prWriter prints pull requests to the writer in a non-interactive way.
+type prWriter struct {
+	format OutputFormat
+	tmpl   *template.Template
+}
+
+// newPRWriter makes a new writer for the given format. Template is required
+// only for the template format.
+func newPRWriter(format OutputFormat, tmpl string) (*prWriter, error) {
+	w := &prWriter{format: format}
+	if format != OutputTemplate {
+		return w, nil
+	}
+
+	if tmpl == "" {
+		return nil, fmt.Errorf("template is required for %q output", OutputTemplate)
+	}
+
+	var err error
+	w.tmpl, err = template.New("pr").
+		Funcs(template.FuncMap{
+			"join": strings.Join,
+			"usernames": func(us []git.User) []string {
+				return lo.Map(us, func(u git.User, _ int) string { return u.Username })
+			},
+		}).
+		Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+
+	return w, nil
+}
+
+// Details returns the details of pull requests, printed in the format.
+func (w *prWriter) Details() engine.Details {
+	switch w.format {
+	case OutputCSV:
+		// last activity is computed from the history
+		return engine.DetailsApprovals | engine.DetailsDiscussions | engine.DetailsCommits | engine.DetailsPipeline
+	case OutputMarkdown:
+		return engine.DetailsApprovals | engine.DetailsDiscussions
+	default:
+		// the whole pull request is printed
+		return engine.DetailsAll
+	}
+}
+
+// Write prints the pull requests.
+func (w *prWriter) Write(out io.Writer, prs []git.PullRequest) error {
+	switch w.format {
+	case OutputJSON:
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(lo.Ternary(prs == nil, []git.PullRequest{}, prs))
+	case OutputJSONL:
+		enc := json.NewEncoder(out)
+		for _, pr := range prs {
+			if err := enc.Encode(pr); err != nil {
+				return fmt.Errorf("encode %s: %w", pr.URL, err)
+			}
+		}
+		return nil
+	case OutputCSV:
+		return w.writeCSV(out, prs)
+	case OutputMarkdown:
+		return w.writeMarkdown(out, prs)
+	case OutputTemplate:
+		for _, pr := range prs {
+			if err := w.tmpl.Execute(out, pr); err != nil {
+				return fmt.Errorf("execute template for %s: %w", pr.URL, err)
+			}
+			if _, err := io.WriteString(out, "\n"); err != nil {
+				return fmt.Errorf("write newline: %w", err)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q", w.format)
+	}
+}
+
+func (w *prWriter) writeCSV(out io.Writer, prs []git.PullRequest) error {
+	cw := csv.NewWriter(out)
+
+	header := []string{
+		"instance", "project", "number", "title", "author", "url", "state", "labels",
+		"created_at", "updated_at", "last_activity_at", "threads_resolved", "threads_total", "approvals", "approvals_required",
+		"satisfies_approval_rules", "pipeline", "rebase_required",
+	}
+
+	if err := cw.Write(header); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	for _, pr := range prs {
+		row := []string{
+			pr.Instance,
+			pr.Project.FullPath,
+			strconv.Itoa(pr.Number),
+			pr.Title,
+			pr.Author.Username,
+			pr.URL,
+			string(pr.State),
+			strings.Join(pr.Labels, ","),
+			pr.CreatedAt.Format(time.RFC3339),
+			pr.UpdatedAt.Format(time.RFC3339),
+			pr.LastActivityAt.Format(time.RFC3339),
+			strconv.Itoa(resolvedThreads(pr)),
+			strconv.Itoa(len(pr.Threads)),
+			strconv.Itoa(len(pr.Approvals.By)),
+			strconv.Itoa(pr.Approvals.Required),
+			strconv.FormatBool(pr.Approvals.SatisfiesRules),
+			string(pr.Pipeline.Status),
+			strconv.FormatBool(pr.Mergeability.RebaseRequired()),
+		}
+
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("write %s: %w", pr.URL, err)
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func (w *prWriter) writeMarkdown(out io.Writer, prs []git.PullRequest) error {
+	esc := strings.NewReplacer("|", `\|`, "\n", " ", "[", `\[`, "]", `\]`)
+
+	lines := []string{
+		"| Project | No. | Title | Author | Created At | Threads | Approvals |",
+		"|---------|-----|-------|--------|------------|---------|-----------|",
+	}
+
+	for _, pr := range prs {
+		lines = append(lines, fmt.Sprintf("| %s | %d | [%s](%s) | %s | %s | %d/%d | %d/%d |",
+			esc.Replace(pr.Project.FullPath),
+			pr.Number,
+			esc.Replace(pr.Title), pr.URL,
+			esc.Replace(pr.Author.Username),
+			pr.CreatedAt.Format("2006-01-02"),
+			resolvedThreads(pr), len(pr.Threads),
+			len(pr.Approvals.By), pr.Approvals.Required,
+		))
+	}
+
+	if _, err := io.WriteString(out, strings.Join(lines, "\n")+"\n"); err != nil {
+		return fmt.Errorf("write table: %w", err)
+	}
+
+	return nil
+}
+
+func resolvedThreads(pr git.PullRequest) int {
+	return lo.CountBy(pr.Threads, func(t git.Comment) bool { return t.Resolved })
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/Semior001/glmrl/pkg/git"
+	"github.com/Semior001/glmrl/pkg/git/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPRWriter_Write(t *testing.T) {
+	createdAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	pr := git.PullRequest{
+		Instance:       "gl",
+		Project:        git.Project{FullPath: "group/repo"},
+		Number:         7,
+		Title:          "fix: \"a|b\", [c]\nsecond",
+		Author:         git.User{Username: "alice"},
+		URL:            "https://example.com/7",
+		State:          git.StateOpen,
+		Labels:         []string{"a", "b"},
+		CreatedAt:      createdAt,
+		UpdatedAt:      createdAt.Add(time.Hour),
+		LastActivityAt: createdAt.Add(2 * time.Hour),
+		Threads:        []git.Comment{{Resolved: true}, {}},
+		Pipeline:       git.Pipeline{Status: git.PipelineStatusSuccess},
+		Mergeability:   git.Mergeability{NeedsRebase: true},
+	}
+	pr.Approvals.By = []git.User{{Username: "bob"}, {Username: "carol"}}
+	pr.Approvals.Required = 2
+	pr.Approvals.SatisfiesRules = true
+
+	const csvHeader = "instance,project,number,title,author,url,state,labels,created_at,updated_at,last_activity_at," +
+		"threads_resolved,threads_total,approvals,approvals_required,satisfies_approval_rules,pipeline,rebase_required\n"
+
+	tests := []struct {
+		name   string
+		format OutputFormat
+		tmpl   string
+		prs    []git.PullRequest
+		want   string
+	}{
+		{name: "json of no pull requests", format: OutputJSON, want: "[]\n"},
+		{name: "jsonl of no pull requests", format: OutputJSONL, want: ""},
+		{name: "csv of no pull requests", format: OutputCSV, want: csvHeader},
+		{
+			name:   "csv quotes separators, quotes and newlines",
+			format: OutputCSV,
+			prs:    []git.PullRequest{pr},
+			want: csvHeader +
+				"gl,group/repo,7,\"fix: \"\"a|b\"\", [c]\nsecond\",alice,https://example.com/7,open,\"a,b\"," +
+				"2023-01-02T03:04:05Z,2023-01-02T04:04:05Z,2023-01-02T05:04:05Z,1,2,2,2,true,success,true\n",
+		},
+		{
+			name:   "markdown escapes pipes, brackets and newlines",
+			format: OutputMarkdown,
+			prs:    []git.PullRequest{pr},
+			want: "| Project | No. | Title | Author | Created At | Threads | Approvals |\n" +
+				"|---------|-----|-------|--------|------------|---------|-----------|\n" +
+				"| group/repo | 7 | [fix: \"a\\|b\", \\[c\\] second](https://example.com/7) | alice | 2023-01-02 | 1/2 | 2/2 |\n",
+		},
+		{
+			name:   "template with helpers",
+			format: OutputTemplate,
+			tmpl:   `{{.Number}} {{join .Labels ","}} {{join (usernames .Approvals.By) ";"}}`,
+			prs:    []git.PullRequest{pr, {Number: 8}},
+			want:   "7 a,b bob;carol\n8  \n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := newPRWriter(tt.format, tt.tmpl)
+			require.NoError(t, err)
+
+			buf := &bytes.Buffer{}
+			require.NoError(t, w.Write(buf, tt.prs))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestPRWriter_Write_json(t *testing.T) {
+	prs := []git.PullRequest{{Number: 1, Title: "first"}, {Number: 2, Title: "second"}}
+
+	t.Run("json", func(t *testing.T) {
+		w, err := newPRWriter(OutputJSON, "")
+		require.NoError(t, err)
+
+		buf := &bytes.Buffer{}
+		require.NoError(t, w.Write(buf, prs))
+
+		var got []git.PullRequest
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+		assert.Equal(t, prs, got)
+	})
+
+	t.Run("jsonl", func(t *testing.T) {
+		w, err := newPRWriter(OutputJSONL, "")
+		require.NoError(t, err)
+
+		buf := &bytes.Buffer{}
+		require.NoError(t, w.Write(buf, prs))
+
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		require.Len(t, lines, 2)
+		for idx, line := range lines {
+			var got git.PullRequest
+			require.NoError(t, json.Unmarshal([]byte(line), &got))
+			assert.Equal(t, prs[idx], got)
+		}
+	})
+}
+
+func TestNewPRWriter_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  OutputFormat
+		tmpl    string
+		wantErr string
+	}{
+		{name: "missing template", format: OutputTemplate, wantErr: `template is required for "template" output`},
+		{name: "invalid template", format: OutputTemplate, tmpl: "{{.Number", wantErr: "parse template"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newPRWriter(tt.format, tt.tmpl)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+
+	w, err := newPRWriter("xml", "")
+	require.NoError(t, err)
+	assert.EqualError(t, w.Write(&bytes.Buffer{}, nil), `unsupported output format "xml"`)
+}
+
+func TestPRWriter_Details(t *testing.T) {
+	tests := []struct {
+		format OutputFormat
+		want   engine.Details
+	}{
+		{format: OutputJSON, want: engine.DetailsAll},
+		{format: OutputJSONL, want: engine.DetailsAll},
+		{format: OutputCSV, want: engine.DetailsAll &^ engine.DetailsDiff},
+		{format: OutputMarkdown, want: engine.DetailsApprovals | engine.DetailsDiscussions},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			w, err := newPRWriter(tt.format, "")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, w.Details())
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"reflect"
+)
+
+// ApplyQueries applies the saved queries, requested in the options.
+// A single query overrides the options, while several queries are
+// shown each in its own tab.
+func (c *List) ApplyQueries(saved map[string]yaml.Node, explicit func(longName string) bool) error {
+	tabs := make([]listTab, 0, len(c.Query))
+	for _, name := range c.Query {
+		query, ok := saved[name]
+		if !ok {
+			return fmt.Errorf("query %q not found in config", name)
+		}
+
+		opts := *c
+		if err := opts.ApplyQuery(&query, explicit); err != nil {
+			return fmt.Errorf("apply query %q: %w", name, err)
+		}
+
+		tabs = append(tabs, listTab{name: name, opts: opts})
+	}
+
+	if len(tabs) == 1 {
+		*c = tabs[0].opts
+		return nil
+	}
+
+	c.tabs = tabs
+	return nil
+}
+
+// ApplyQuery overrides the options with the values of the saved query.
+// Options, explicitly set in the command line, are kept as is, so the
+// user is able to adjust the saved query on the fly. Options, missing in
+// the query, keep their defaults.
+func (c *List) ApplyQuery(query *yaml.Node, explicit func(longName string) bool) error {
+	merged := *c
+	if err := query.Decode(&merged); err != nil {
+		return fmt.Errorf("decode query: %w", err)
+	}
+
+	restoreExplicit(reflect.ValueOf(&merged).Elem(), reflect.ValueOf(c).Elem(), "", explicit)
+
+	*c = merged
+	return nil
+}
+
+// restoreExplicit copies the values of explicitly set options from src to dst.
+// Long names of the options are built from struct tags the same way as the
+// flags parser does, joining the namespaces of the groups with a dot.
+func restoreExplicit(dst, src reflect.Value, namespace string, explicit func(longName string) bool) {
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Type().Field(i)
+		if field.Anonymous || !field.IsExported() {
+			continue
+		}
+
+		if long := field.Tag.Get("long"); long != "" {
+			if explicit(namespace + long) {
+				dst.Field(i).Set(src.Field(i))
+			}
+			continue
+		}
+
+		if field.Type.Kind() != reflect.Struct {
+			continue
+		}
+
+		ns := namespace
+		if n := field.Tag.Get("namespace"); n != "" {
+			ns += n + "."
+		}
+
+		restoreExplicit(dst.Field(i), src.Field(i), ns, explicit)
+	}
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"github.com/Semior001/glmrl/pkg/git"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+	"testing"
+	"time"
+)
+
+// defaultList returns the options with defaults of the flags, modified by fn.
+func defaultList(fn func(l *List)) List {
+	l := List{Action: "open", PollInterval: 5 * time.Minute, Output: OutputTable}
+	l.Sort.By, l.Sort.Order = "created", "desc"
+	if fn != nil {
+		fn(&l)
+	}
+	return l
+}
+
+func yamlNode(t *testing.T, src string) yaml.Node {
+	t.Helper()
+	var n yaml.Node
+	require.NoError(t, yaml.Unmarshal([]byte(src), &n))
+	return n
+}
+
+func TestList_ApplyQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     List
+		explicit []string
+		query    string
+		want     List
+		wantErr  string
+	}{
+		{
+			name:  "query overrides defaults, missing options keep them",
+			opts:  defaultList(nil),
+			query: "state: merged\nlabels:\n  include: [a]\nsort:\n  by: updated\npoll-interval: 1m\n",
+			want: defaultList(func(l *List) {
+				l.State = git.StateMerged
+				l.Labels.Include = []string{"a"}
+				l.Sort.By = "updated"
+				l.PollInterval = time.Minute
+			}),
+		},
+		{
+			name: "explicitly set options are kept, including the ones in groups",
+			opts: defaultList(func(l *List) {
+				l.State = git.StateOpen
+				l.Labels.Include = []string{"cli"}
+				l.Size.MaxLines = 10
+				l.Sort.Order = "asc"
+				l.ApprovedByMe = "false"
+			}),
+			explicit: []string{"state", "labels.include", "size.max-lines", "sort.order", "approved-by-me"},
+			query: "state: merged\nlabels:\n  include: [a]\n  exclude: [b]\nsize:\n  max-lines: 100\n  max-files: 5\n" +
+				"sort:\n  order: desc\napproved-by-me: true\nreviewer: alice\n",
+			want: defaultList(func(l *List) {
+				l.State = git.StateOpen
+				l.Labels = FilterGroup{Include: []string{"cli"}, Exclude: []string{"b"}}
+				l.Size.MaxLines, l.Size.MaxFiles = 10, 5
+				l.Sort.Order = "asc"
+				l.ApprovedByMe = "false"
+				l.Reviewer = "alice"
+			}),
+		},
+		{
+			name:  "options, ignored in queries, are not set",
+			opts:  defaultList(func(l *List) { l.Query = []string{"mine"} }),
+			query: "query: [other]\nsearch: fix\n",
+			want: defaultList(func(l *List) {
+				l.Query = []string{"mine"}
+				l.Search = "fix"
+			}),
+		},
+		{
+			name:    "invalid value",
+			opts:    defaultList(nil),
+			query:   "size:\n  max-lines: many\n",
+			wantErr: "decode query",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := yamlNode(t, tt.query)
+			opts := tt.opts
+
+			err := opts.ApplyQuery(&query, func(longName string) bool { return lo.Contains(tt.explicit, longName) })
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				assert.Equal(t, tt.opts, opts, "options must not be changed on error")
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, opts)
+		})
+	}
+}
+
+func TestList_ApplyQueries(t *testing.T) {
+	saved := map[string]yaml.Node{
+		"mine":   yamlNode(t, "authors:\n  include: [me]\n"),
+		"review": yamlNode(t, "reviewer: me\nstate: open\n"),
+	}
+	notExplicit := func(string) bool { return false }
+
+	t.Run("single query overrides the options", func(t *testing.T) {
+		opts := defaultList(func(l *List) { l.Query = []string{"review"} })
+		require.NoError(t, opts.ApplyQueries(saved, notExplicit))
+
+		assert.Equal(t, defaultList(func(l *List) {
+			l.Query = []string{"review"}
+			l.Reviewer = "me"
+			l.State = git.StateOpen
+		}), opts)
+	})
+
+	t.Run("several queries are shown in tabs", func(t *testing.T) {
+		opts := defaultList(func(l *List) {
+			l.Query = []string{"mine", "review"}
+			l.Search = "fix"
+		})
+		require.NoError(t, opts.ApplyQueries(saved, func(longName string) bool { return longName == "search" }))
+
+		assert.Empty(t, opts.Reviewer, "options of tabs must not leak into the common ones")
+		require.Len(t, opts.tabs, 2)
+
+		assert.Equal(t, "mine", opts.tabs[0].name)
+		assert.Equal(t, []string{"me"}, opts.tabs[0].opts.Authors.Include)
+		assert.Equal(t, "fix", opts.tabs[0].opts.Search)
+
+		assert.Equal(t, "review", opts.tabs[1].name)
+		assert.Equal(t, "me", opts.tabs[1].opts.Reviewer)
+		assert.Empty(t, opts.tabs[1].opts.Authors.Include)
+		assert.Equal(t, "fix", opts.tabs[1].opts.Search)
+	})
+
+	t.Run("unknown query", func(t *testing.T) {
+		opts := defaultList(func(l *List) { l.Query = []string{"mine", "other"} })
+		assert.EqualError(t, opts.ApplyQueries(saved, notExplicit), `query "other" not found in config`)
+	})
+}
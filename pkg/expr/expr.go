@@ -0,0 +1,244 @@
+// Package expr implements a small typed expression language to filter values
+// by their fields, e.g.:
+//
+//	approvals.by contains "alice" && age > 48h && !draft
+//
+// Expressions are type-checked against the set of fields at compile time.
+package expr
+
+import (
+	"fmt"
+	"github.com/samber/lo"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Type is a type of value in expressions.
+type Type int
+
+// Supported types.
+const (
+	TypeBool     Type = iota + 1 // bool
+	TypeInt                      // int
+	TypeString                   // string
+	TypeDuration                 // time.Duration
+	TypeStrings                  // []string
+)
+
+// String returns the name of the type.
+func (t Type) String() string {
+	switch t {
+	case TypeBool:
+		return "bool"
+	case TypeInt:
+		return "int"
+	case TypeString:
+		return "string"
+	case TypeDuration:
+		return "duration"
+	case TypeStrings:
+		return "list"
+	default:
+		return "unknown"
+	}
+}
+
+// Field describes a field of T available in expressions.
+type Field[T any] struct {
+	Type        Type
+	Description string
+	// Get extracts the value of the field, it must return the value
+	// of the go type corresponding to the Type of the field.
+	Get func(T) any
+}
+
+// Program is a compiled expression.
+type Program[T any] struct {
+	src    string
+	eval   func(T) any
+	fields []string
+}
+
+// Compile parses the source and checks it against the fields.
+// Expression must evaluate to bool.
+func Compile[T any](src string, fields map[string]Field[T]) (*Program[T], error) {
+	n, err := parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+
+	c := compiler[T]{fields: fields, used: map[string]bool{}}
+	typ, eval, err := c.compile(n)
+	if err != nil {
+		return nil, fmt.Errorf("check: %w", err)
+	}
+
+	if typ != TypeBool {
+		return nil, fmt.Errorf("check: expression must be bool, got %s", typ)
+	}
+
+	used := lo.Keys(c.used)
+	sort.Strings(used)
+
+	return &Program[T]{src: src, eval: eval, fields: used}, nil
+}
+
+// Match evaluates the program against the value.
+func (p *Program[T]) Match(v T) bool { return p.eval(v).(bool) }
+
+// String returns the source of the program.
+func (p *Program[T]) String() string { return p.src }
+
+// Fields returns the sorted names of the fields, referenced by the program.
+func (p *Program[T]) Fields() []string { return p.fields }
+
+// Help returns the list of fields with their types and descriptions.
+func Help[T any](fields map[string]Field[T]) string {
+	names := lo.Keys(fields)
+	sort.Strings(names)
+
+	sb := &strings.Builder{}
+	for _, name := range names {
+		_, _ = fmt.Fprintf(sb, "%-24s %-9s %s\n", name, fields[name].Type, fields[name].Description)
+	}
+	return sb.String()
+}
+
+type compiler[T any] struct {
+	fields map[string]Field[T]
+	used   map[string]bool // names of the referenced fields
+}
+
+func (c compiler[T]) compile(n node) (Type, func(T) any, error) {
+	switch n := n.(type) {
+	case literalNode:
+		val := n.val
+		return n.typ, func(T) any { return val }, nil
+	case listNode:
+		items := make([]string, len(n.items))
+		for idx, item := range n.items {
+			lit := item.(literalNode)
+			if lit.typ != TypeString {
+				return 0, nil, fmt.Errorf("at %d: only lists of strings are supported, got %s", lit.pos, lit.typ)
+			}
+			items[idx] = lit.val.(string)
+		}
+		return TypeStrings, func(T) any { return items }, nil
+	case identNode:
+		f, ok := c.fields[n.name]
+		if !ok {
+			return 0, nil, fmt.Errorf("at %d: unknown field %q", n.pos, n.name)
+		}
+		c.used[n.name] = true
+		return f.Type, f.Get, nil
+	case unaryNode:
+		typ, operand, err := c.compile(n.operand)
+		if err != nil {
+			return 0, nil, err
+		}
+		if typ != TypeBool {
+			return 0, nil, fmt.Errorf("at %d: operator \"!\" expects bool, got %s", n.pos, typ)
+		}
+		return TypeBool, func(v T) any { return !operand(v).(bool) }, nil
+	case binaryNode:
+		return c.compileBinary(n)
+	default:
+		return 0, nil, fmt.Errorf("at %d: unexpected node %T", n.position(), n)
+	}
+}
+
+func (c compiler[T]) compileBinary(n binaryNode) (Type, func(T) any, error) {
+	lt, left, err := c.compile(n.left)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	rt, right, err := c.compile(n.right)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	mismatch := func() error {
+		return fmt.Errorf("at %d: operator %q is not defined for %s and %s", n.pos, n.op, lt, rt)
+	}
+
+	switch n.op {
+	case "&&", "||":
+		if lt != TypeBool || rt != TypeBool {
+			return 0, nil, mismatch()
+		}
+		if n.op == "&&" {
+			return TypeBool, func(v T) any { return left(v).(bool) && right(v).(bool) }, nil
+		}
+		return TypeBool, func(v T) any { return left(v).(bool) || right(v).(bool) }, nil
+	case "==", "!=":
+		if lt != rt || lt == TypeStrings {
+			return 0, nil, mismatch()
+		}
+		neg := n.op == "!="
+		return TypeBool, func(v T) any { return (left(v) == right(v)) != neg }, nil
+	case "<", "<=", ">", ">=":
+		if lt != rt || (lt != TypeInt && lt != TypeDuration) {
+			return 0, nil, mismatch()
+		}
+		op := n.op
+		return TypeBool, func(v T) any { return compare(op, toInt64(left(v)), toInt64(right(v))) }, nil
+	case "contains":
+		switch {
+		case lt == TypeStrings && rt == TypeString:
+			return TypeBool, func(v T) any { return lo.Contains(left(v).([]string), right(v).(string)) }, nil
+		case lt == TypeString && rt == TypeString:
+			return TypeBool, func(v T) any { return strings.Contains(left(v).(string), right(v).(string)) }, nil
+		default:
+			return 0, nil, mismatch()
+		}
+	case "in":
+		if lt != TypeString || rt != TypeStrings {
+			return 0, nil, mismatch()
+		}
+		return TypeBool, func(v T) any { return lo.Contains(right(v).([]string), left(v).(string)) }, nil
+	case "intersects":
+		if lt != TypeStrings || rt != TypeStrings {
+			return 0, nil, mismatch()
+		}
+		return TypeBool, func(v T) any { return lo.Some(left(v).([]string), right(v).([]string)) }, nil
+	case "matches":
+		lit, isLit := n.right.(literalNode)
+		if lt != TypeString || !isLit || lit.typ != TypeString {
+			return 0, nil, fmt.Errorf("at %d: operator \"matches\" expects string and a string literal", n.pos)
+		}
+		re, err := regexp.Compile(lit.val.(string))
+		if err != nil {
+			return 0, nil, fmt.Errorf("at %d: invalid regular expression: %w", lit.pos, err)
+		}
+		return TypeBool, func(v T) any { return re.MatchString(left(v).(string)) }, nil
+	default:
+		return 0, nil, fmt.Errorf("at %d: unknown operator %q", n.pos, n.op)
+	}
+}
+
+func toInt64(v any) int64 {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case time.Duration:
+		return int64(v)
+	default:
+		panic(fmt.Sprintf("unexpected type %T", v))
+	}
+}
+
+func compare(op string, a, b int64) bool {
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	default:
+		return a >= b
+	}
+}
@@ -0,0 +1,256 @@
+package expr
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type testPR struct {
+	Title      string
+	Draft      bool
+	Approvals  int
+	Age        time.Duration
+	Labels     []string
+	ApprovedBy []string
+}
+
+var testFields = map[string]Field[testPR]{
+	"title":        {Type: TypeString, Get: func(pr testPR) any { return pr.Title }},
+	"draft":        {Type: TypeBool, Get: func(pr testPR) any { return pr.Draft }},
+	"approvals":    {Type: TypeInt, Get: func(pr testPR) any { return pr.Approvals }},
+	"age":          {Type: TypeDuration, Get: func(pr testPR) any { return pr.Age }},
+	"labels":       {Type: TypeStrings, Get: func(pr testPR) any { return pr.Labels }},
+	"approvals.by": {Type: TypeStrings, Get: func(pr testPR) any { return pr.ApprovedBy }},
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    node
+		wantErr string
+	}{
+		{
+			name: "and binds tighter than or",
+			src:  "draft || approvals > 1 && age < 48h",
+			want: binaryNode{op: "||", pos: 6,
+				left: identNode{name: "draft", pos: 0},
+				right: binaryNode{op: "&&", pos: 23,
+					left: binaryNode{op: ">", pos: 19,
+						left:  identNode{name: "approvals", pos: 9},
+						right: literalNode{typ: TypeInt, val: 1, pos: 21},
+					},
+					right: binaryNode{op: "<", pos: 30,
+						left:  identNode{name: "age", pos: 26},
+						right: literalNode{typ: TypeDuration, val: 48 * time.Hour, pos: 32},
+					},
+				},
+			},
+		},
+		{
+			name: "parentheses override precedence",
+			src:  "(draft || true) && false",
+			want: binaryNode{op: "&&", pos: 16,
+				left: binaryNode{op: "||", pos: 7,
+					left:  identNode{name: "draft", pos: 1},
+					right: literalNode{typ: TypeBool, val: true, pos: 10},
+				},
+				right: literalNode{typ: TypeBool, val: false, pos: 19},
+			},
+		},
+		{
+			name: "negation applies to the comparison",
+			src:  `!approvals.by contains "alice"`,
+			want: unaryNode{op: "!", pos: 0,
+				operand: binaryNode{op: "contains", pos: 14,
+					left:  identNode{name: "approvals.by", pos: 1},
+					right: literalNode{typ: TypeString, val: "alice", pos: 23},
+				},
+			},
+		},
+		{
+			name: "list of strings with escapes",
+			src:  `labels intersects ["a", "b\"c"]`,
+			want: binaryNode{op: "intersects", pos: 7,
+				left: identNode{name: "labels", pos: 0},
+				right: listNode{pos: 18, items: []node{
+					literalNode{typ: TypeString, val: "a", pos: 19},
+					literalNode{typ: TypeString, val: `b"c`, pos: 24},
+				}},
+			},
+		},
+		{
+			name: "empty list",
+			src:  `title in []`,
+			want: binaryNode{op: "in", pos: 6,
+				left:  identNode{name: "title", pos: 0},
+				right: listNode{pos: 9},
+			},
+		},
+		{name: "unterminated string", src: `title == "abc`, wantErr: `at 9: unterminated string`},
+		{name: "unexpected character", src: `approvals = 1`, wantErr: `at 10: unexpected character '='`},
+		{name: "unexpected end", src: `draft &&`, wantErr: `at 8: unexpected end of expression`},
+		{name: "unclosed parenthesis", src: `(draft`, wantErr: `at 6: expected ")", got ""`},
+		{name: "trailing tokens", src: `draft true`, wantErr: `at 6: unexpected "true"`},
+		{name: "operator instead of operand", src: `contains "a"`, wantErr: `at 0: unexpected operator "contains"`},
+		{name: "ident in list", src: `title in [title]`, wantErr: `at 10: expected literal, got "title"`},
+		{name: "unclosed list", src: `title in ["a" "b"]`, wantErr: `at 14: expected "," or "]", got "b"`},
+		{name: "invalid duration", src: `age > 48x`, wantErr: `at 6: invalid duration "48x"`},
+		{name: "invalid number", src: `approvals > 1.5`, wantErr: `at 12: invalid number "1.5"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := parse(tt.src)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, n)
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		src     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{src: "48h", want: 48 * time.Hour},
+		{src: "90m", want: 90 * time.Minute},
+		{src: "2d", want: 48 * time.Hour},
+		{src: "1.5d", want: 36 * time.Hour},
+		{src: "1w2d12h", want: 9*24*time.Hour + 12*time.Hour},
+		{src: "1h30m", want: 90 * time.Minute},
+		{src: "h", wantErr: true},
+		{src: "12", wantErr: true},
+		{src: "3y", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			d, err := parseDuration(tt.src)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, d)
+		})
+	}
+}
+
+func TestCompile_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{name: "parse error", src: `(`, wantErr: `parse: at 1: unexpected end of expression`},
+		{name: "unknown field", src: `author == "alice"`, wantErr: `check: at 0: unknown field "author"`},
+		{name: "unknown field in nested expression", src: `draft && !(reviewers contains "a")`,
+			wantErr: `check: at 11: unknown field "reviewers"`},
+		{name: "not a bool", src: `approvals`, wantErr: `check: expression must be bool, got int`},
+		{name: "negation of int", src: `!approvals`, wantErr: `check: at 0: operator "!" expects bool, got int`},
+		{name: "and of int", src: `draft && approvals`,
+			wantErr: `check: at 6: operator "&&" is not defined for bool and int`},
+		{name: "int compared to duration", src: `age > 2`,
+			wantErr: `check: at 4: operator ">" is not defined for duration and int`},
+		{name: "ordering of strings", src: `title < "b"`,
+			wantErr: `check: at 6: operator "<" is not defined for string and string`},
+		{name: "equality of lists", src: `labels == ["a"]`,
+			wantErr: `check: at 7: operator "==" is not defined for list and list`},
+		{name: "contains of int", src: `labels contains 1`,
+			wantErr: `check: at 7: operator "contains" is not defined for list and int`},
+		{name: "in of list", src: `labels in ["a"]`,
+			wantErr: `check: at 7: operator "in" is not defined for list and list`},
+		{name: "intersects with string", src: `labels intersects "a"`,
+			wantErr: `check: at 7: operator "intersects" is not defined for list and string`},
+		{name: "matches with field", src: `title matches title`,
+			wantErr: `check: at 6: operator "matches" expects string and a string literal`},
+		{name: "invalid regexp", src: `title matches "("`, wantErr: `check: at 14: invalid regular expression`},
+		{name: "list of ints", src: `title in ["a", 1]`,
+			wantErr: `check: at 15: only lists of strings are supported, got int`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.src, testFields)
+			require.Error(t, err)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestProgram_Match(t *testing.T) {
+	pr := testPR{
+		Title:      "feat: add filters",
+		Approvals:  2,
+		Age:        72 * time.Hour,
+		Labels:     []string{"backend", "urgent"},
+		ApprovedBy: []string{"alice", "bob"},
+	}
+
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{src: `true || false && false`, want: true},
+		{src: `(true || false) && false`, want: false},
+		{src: `!draft && approvals >= 2`, want: true},
+		{src: `!(draft || approvals >= 2)`, want: false},
+		{src: `!!draft`, want: false},
+		{src: `approvals == 2`, want: true},
+		{src: `approvals != 2`, want: false},
+		{src: `approvals < 2 || approvals > 2`, want: false},
+		{src: `age > 48h`, want: true},
+		{src: `age <= 3d`, want: true},
+		{src: `age < 2d12h`, want: false},
+		{src: `age == 1w`, want: false},
+		{src: `approvals.by contains "alice"`, want: true},
+		{src: `approvals.by contains "carol"`, want: false},
+		{src: `title contains "add"`, want: true},
+		{src: `title contains "remove"`, want: false},
+		{src: `"alice" in approvals.by`, want: true},
+		{src: `title in ["a", "b"]`, want: false},
+		{src: `labels intersects ["frontend", "urgent"]`, want: true},
+		{src: `labels intersects []`, want: false},
+		{src: `title matches "^feat(\\(.+\\))?:"`, want: true},
+		{src: `title matches "^fix:"`, want: false},
+		{src: `title == "feat: add filters" && draft == false`, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			prog, err := Compile(tt.src, testFields)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, prog.Match(pr))
+			assert.Equal(t, tt.src, prog.String())
+		})
+	}
+}
+
+func TestProgram_Fields(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{src: `true`, want: []string{}},
+		{src: `!draft`, want: []string{"draft"}},
+		{src: `title contains "a" && (age > 48h || title matches "b")`, want: []string{"age", "title"}},
+		{src: `"alice" in approvals.by && labels intersects ["a"]`, want: []string{"approvals.by", "labels"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			prog, err := Compile(tt.src, testFields)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, prog.Fields())
+		})
+	}
+}
@@ -0,0 +1,385 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokDuration
+	tokOp
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// lex splits the source into tokens.
+func lex(src string) ([]token, error) {
+	var toks []token
+	rs := []rune(src)
+
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			toks = append(toks, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			toks = append(toks, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case r == '[':
+			toks = append(toks, token{kind: tokLBracket, text: "[", pos: i})
+			i++
+		case r == ']':
+			toks = append(toks, token{kind: tokRBracket, text: "]", pos: i})
+			i++
+		case r == ',':
+			toks = append(toks, token{kind: tokComma, text: ",", pos: i})
+			i++
+		case r == '"':
+			j := i + 1
+			for ; j < len(rs) && rs[j] != '"'; j++ {
+				if rs[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("at %d: unterminated string", i)
+			}
+			s, err := strconv.Unquote(string(rs[i : j+1]))
+			if err != nil {
+				return nil, fmt.Errorf("at %d: invalid string: %w", i, err)
+			}
+			toks = append(toks, token{kind: tokString, text: s, pos: i})
+			i = j + 1
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || unicode.IsLetter(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			text := string(rs[i:j])
+			kind := tokNumber
+			if strings.IndexFunc(text, unicode.IsLetter) >= 0 {
+				kind = tokDuration
+			}
+			toks = append(toks, token{kind: kind, text: text, pos: i})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_' || rs[j] == '.') {
+				j++
+			}
+			toks = append(toks, token{kind: tokIdent, text: string(rs[i:j]), pos: i})
+			i = j
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!"} {
+				if strings.HasPrefix(string(rs[i:]), candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("at %d: unexpected character %q", i, r)
+			}
+			toks = append(toks, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+
+	return append(toks, token{kind: tokEOF, pos: len(rs)}), nil
+}
+
+// node is a node of the syntax tree.
+type node interface{ position() int }
+
+type binaryNode struct {
+	op          string
+	left, right node
+	pos         int
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+	pos     int
+}
+
+type identNode struct {
+	name string
+	pos  int
+}
+
+type literalNode struct {
+	typ Type
+	val any
+	pos int
+}
+
+type listNode struct {
+	items []node
+	pos   int
+}
+
+func (n binaryNode) position() int  { return n.pos }
+func (n unaryNode) position() int   { return n.pos }
+func (n identNode) position() int   { return n.pos }
+func (n literalNode) position() int { return n.pos }
+func (n listNode) position() int    { return n.pos }
+
+// comparison operators, including the keyword ones
+var comparisons = map[string]bool{
+	"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true,
+	"contains": true, "in": true, "intersects": true, "matches": true,
+}
+
+// parser is a recursive descent parser with the following grammar:
+//
+//	or      = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | cmp
+//	cmp     = primary [ op primary ]
+//	primary = "(" or ")" | list | literal | ident
+//	list    = "[" [ literal { "," literal } ] "]"
+type parser struct {
+	toks []token
+	idx  int
+}
+
+func parse(src string) (node, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{toks: toks}
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("at %d: unexpected %q", tok.pos, tok.text)
+	}
+
+	return n, nil
+}
+
+func (p *parser) peek() token { return p.toks[p.idx] }
+
+func (p *parser) next() token {
+	tok := p.toks[p.idx]
+	if tok.kind != tokEOF {
+		p.idx++
+	}
+	return tok
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for tok := p.peek(); tok.kind == tokOp && tok.text == "||"; tok = p.peek() {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "||", left: left, right: right, pos: tok.pos}
+	}
+
+	return left, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for tok := p.peek(); tok.kind == tokOp && tok.text == "&&"; tok = p.peek() {
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "&&", left: left, right: right, pos: tok.pos}
+	}
+
+	return left, nil
+}
+
+func (p *parser) unary() (node, error) {
+	if tok := p.peek(); tok.kind == tokOp && tok.text == "!" {
+		p.next()
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{op: "!", operand: operand, pos: tok.pos}, nil
+	}
+
+	return p.cmp()
+}
+
+func (p *parser) cmp() (node, error) {
+	left, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	if (tok.kind != tokOp && tok.kind != tokIdent) || !comparisons[tok.text] {
+		return left, nil
+	}
+
+	p.next()
+	right, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	return binaryNode{op: tok.text, left: left, right: right, pos: tok.pos}, nil
+}
+
+func (p *parser) primary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokLParen:
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, fmt.Errorf("at %d: expected \")\", got %q", closing.pos, closing.text)
+		}
+		return n, nil
+	case tokLBracket:
+		return p.list(tok)
+	case tokIdent:
+		switch tok.text {
+		case "true", "false":
+			return literalNode{typ: TypeBool, val: tok.text == "true", pos: tok.pos}, nil
+		}
+		if comparisons[tok.text] {
+			return nil, fmt.Errorf("at %d: unexpected operator %q", tok.pos, tok.text)
+		}
+		return identNode{name: tok.text, pos: tok.pos}, nil
+	case tokString, tokNumber, tokDuration:
+		return p.literal(tok)
+	case tokEOF:
+		return nil, fmt.Errorf("at %d: unexpected end of expression", tok.pos)
+	default:
+		return nil, fmt.Errorf("at %d: unexpected %q", tok.pos, tok.text)
+	}
+}
+
+func (p *parser) list(opening token) (node, error) {
+	l := listNode{pos: opening.pos}
+	if p.peek().kind == tokRBracket {
+		p.next()
+		return l, nil
+	}
+
+	for {
+		item, err := p.literal(p.next())
+		if err != nil {
+			return nil, err
+		}
+		l.items = append(l.items, item)
+
+		switch tok := p.next(); tok.kind {
+		case tokComma:
+			continue
+		case tokRBracket:
+			return l, nil
+		default:
+			return nil, fmt.Errorf("at %d: expected \",\" or \"]\", got %q", tok.pos, tok.text)
+		}
+	}
+}
+
+func (p *parser) literal(tok token) (node, error) {
+	switch tok.kind {
+	case tokString:
+		return literalNode{typ: TypeString, val: tok.text, pos: tok.pos}, nil
+	case tokNumber:
+		v, err := strconv.Atoi(tok.text)
+		if err != nil {
+			return nil, fmt.Errorf("at %d: invalid number %q: %w", tok.pos, tok.text, err)
+		}
+		return literalNode{typ: TypeInt, val: v, pos: tok.pos}, nil
+	case tokDuration:
+		v, err := parseDuration(tok.text)
+		if err != nil {
+			return nil, fmt.Errorf("at %d: invalid duration %q: %w", tok.pos, tok.text, err)
+		}
+		return literalNode{typ: TypeDuration, val: v, pos: tok.pos}, nil
+	default:
+		return nil, fmt.Errorf("at %d: expected literal, got %q", tok.pos, tok.text)
+	}
+}
+
+// parseDuration parses a duration in go format, additionally supporting
+// days ("d") and weeks ("w") units, e.g. "1w2d12h".
+func parseDuration(s string) (time.Duration, error) {
+	var total time.Duration
+	for s != "" {
+		numEnd := strings.IndexFunc(s, unicode.IsLetter)
+		if numEnd <= 0 {
+			return 0, fmt.Errorf("missing unit or number in %q", s)
+		}
+
+		unitEnd := strings.IndexFunc(s[numEnd:], func(r rune) bool { return !unicode.IsLetter(r) })
+		if unitEnd < 0 {
+			unitEnd = len(s) - numEnd
+		}
+
+		num, unit := s[:numEnd], s[numEnd:numEnd+unitEnd]
+		s = s[numEnd+unitEnd:]
+
+		mult := time.Duration(0)
+		switch unit {
+		case "d":
+			mult = 24 * time.Hour
+		case "w":
+			mult = 7 * 24 * time.Hour
+		default:
+			d, err := time.ParseDuration(num + unit)
+			if err != nil {
+				return 0, err
+			}
+			total += d
+			continue
+		}
+
+		n, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parse number %q: %w", num, err)
+		}
+		total += time.Duration(n * float64(mult))
+	}
+
+	return total, nil
+}
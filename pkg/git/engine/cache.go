@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Semior001/glmrl/pkg/git"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// DiskCache wraps the engine and keeps the details of pull requests on disk,
+// so that they are reused across polls and restarts. Details are reused as
+// long as the pull request is not updated. Pipelines are reloaded until they
+// are settled, as their status changes without updating the pull request.
+type DiskCache struct {
+	Interface
+	dir string
+}
+
+// cacheEntry is the content of a single cache file.
+type cacheEntry struct {
+	UpdatedAt time.Time       `json:"updated_at"`
+	Details   Details         `json:"details"`
+	PR        git.PullRequest `json:"pr"`
+}
+
+// NewDiskCache makes a new cache of the engine's pull requests in the given directory.
+func NewDiskCache(eng Interface, dir string) *DiskCache {
+	return &DiskCache{Interface: eng, dir: dir}
+}
+
+// LoadDetails loads the details, missing in the cache, from the engine.
+func (c *DiskCache) LoadDetails(ctx context.Context, pr git.PullRequest, details Details) (git.PullRequest, error) {
+	path := c.path(pr)
+
+	entry, ok := c.read(path)
+	if !ok || !entry.UpdatedAt.Equal(pr.UpdatedAt) {
+		entry = cacheEntry{UpdatedAt: pr.UpdatedAt}
+	}
+
+	missing := details &^ entry.Details
+	if details.Has(DetailsPipeline) && !entry.PR.Pipeline.Status.Settled() {
+		missing |= DetailsPipeline
+	}
+
+	// all cached details are reused, as engines complete the
+	// history of events with the missing ones
+	pr = copyDetails(pr, entry.PR, entry.Details&^missing)
+
+	if missing == DetailsNone {
+		return pr, nil
+	}
+
+	pr, err := c.Interface.LoadDetails(ctx, pr, missing)
+	if err != nil {
+		return git.PullRequest{}, err
+	}
+
+	entry.Details |= details
+	entry.PR = copyDetails(entry.PR, pr, entry.Details)
+	c.write(path, entry)
+
+	return pr, nil
+}
+
+// Clear removes all cached pull requests.
+func (c *DiskCache) Clear() error {
+	if err := os.RemoveAll(c.dir); err != nil {
+		return fmt.Errorf("remove %s: %w", c.dir, err)
+	}
+	return nil
+}
+
+func (c *DiskCache) path(pr git.PullRequest) string {
+	return filepath.Join(c.dir, url.PathEscape(pr.Project.FullPath), strconv.Itoa(pr.Number)+".json")
+}
+
+func (c *DiskCache) read(path string) (cacheEntry, bool) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("[WARN] read cache %s: %v", path, err)
+		}
+		return cacheEntry{}, false
+	}
+
+	var entry cacheEntry
+	if err = json.Unmarshal(b, &entry); err != nil {
+		log.Printf("[WARN] decode cache %s: %v", path, err)
+		return cacheEntry{}, false
+	}
+
+	return entry, true
+}
+
+// write stores the entry, errors are only logged, as the cache is optional.
+func (c *DiskCache) write(path string, entry cacheEntry) {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("[WARN] encode cache %s: %v", path, err)
+		return
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		log.Printf("[WARN] make cache dir for %s: %v", path, err)
+		return
+	}
+
+	// write to a temporary file first, so that a concurrent
+	// reader never sees a partially written file
+	tmp := fmt.Sprintf("%s.%d.tmp", path, time.Now().UnixNano())
+	if err = os.WriteFile(tmp, b, 0o600); err != nil {
+		log.Printf("[WARN] write cache %s: %v", path, err)
+		return
+	}
+
+	if err = os.Rename(tmp, path); err != nil {
+		log.Printf("[WARN] replace cache %s: %v", path, err)
+		_ = os.Remove(tmp)
+	}
+}
+
+// copyDetails copies the given details from src to dst.
+func copyDetails(dst, src git.PullRequest, details Details) git.PullRequest {
+	if details.Has(DetailsApprovals) {
+		dst.Approvals.By = src.Approvals.By
+		dst.Approvals.SatisfiesRules = src.Approvals.SatisfiesRules
+		dst.Approvals.Required = src.Approvals.Required
+	}
+
+	// commits and discussions share the history
+	if details.Has(DetailsDiscussions) || details.Has(DetailsCommits) {
+		dst.History = src.History
+	}
+
+	if details.Has(DetailsDiscussions) {
+		dst.Threads = src.Threads
+	}
+
+	if details.Has(DetailsPipeline) {
+		dst.Pipeline = src.Pipeline
+	}
+
+	if details.Has(DetailsDiff) {
+		dst.Diff = src.Diff
+	}
+
+	return dst
+}
@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"context"
+	"github.com/Semior001/glmrl/pkg/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// detailsEngine fills the requested details and records them,
+// values depend on the number of the call to tell them apart.
+type detailsEngine struct {
+	Interface
+	pipeline git.PipelineStatus
+	calls    []Details
+}
+
+func (e *detailsEngine) LoadDetails(_ context.Context, pr git.PullRequest, details Details) (git.PullRequest, error) {
+	e.calls = append(e.calls, details)
+	n := len(e.calls)
+
+	if details.Has(DetailsApprovals) {
+		pr.Approvals.By = []git.User{{Username: "alice"}}
+		pr.Approvals.SatisfiesRules = true
+	}
+	if details.Has(DetailsDiscussions) {
+		pr.Threads = []git.Comment{{DiscussionID: "d", Body: "comment"}}
+		pr.History = append(pr.History, git.Event{ID: "c", Type: git.EventTypeCommented})
+	}
+	if details.Has(DetailsPipeline) {
+		pr.Pipeline = git.Pipeline{Status: e.pipeline, URL: "pipeline"}
+	}
+	if details.Has(DetailsDiff) {
+		pr.Diff = git.DiffStats{Files: n}
+	}
+
+	return pr, nil
+}
+
+func TestDiskCache_LoadDetails(t *testing.T) {
+	ctx := context.Background()
+	updatedAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	pr := git.PullRequest{Project: git.Project{FullPath: "group/project"}, Number: 1, UpdatedAt: updatedAt}
+
+	t.Run("warm hit", func(t *testing.T) {
+		dir := t.TempDir()
+		eng := &detailsEngine{pipeline: git.PipelineStatusSuccess}
+		first, err := NewDiskCache(eng, dir).LoadDetails(ctx, pr, DetailsAll)
+		require.NoError(t, err)
+
+		// a new instance reads the details from the disk, as after a restart
+		got, err := NewDiskCache(eng, dir).LoadDetails(ctx, pr, DetailsAll)
+		require.NoError(t, err)
+
+		assert.Equal(t, []Details{DetailsAll}, eng.calls, "details must not be reloaded")
+		assert.Equal(t, first, got)
+	})
+
+	t.Run("miss after update", func(t *testing.T) {
+		eng := &detailsEngine{pipeline: git.PipelineStatusSuccess}
+		c := NewDiskCache(eng, t.TempDir())
+
+		_, err := c.LoadDetails(ctx, pr, DetailsDiff)
+		require.NoError(t, err)
+
+		updated := pr
+		updated.UpdatedAt = updatedAt.Add(time.Hour)
+		got, err := c.LoadDetails(ctx, updated, DetailsDiff)
+		require.NoError(t, err)
+
+		assert.Equal(t, []Details{DetailsDiff, DetailsDiff}, eng.calls)
+		assert.Equal(t, 2, got.Diff.Files, "stale details must be replaced")
+		assert.Equal(t, updated.UpdatedAt, got.UpdatedAt)
+
+		// the updated entry is cached
+		_, err = c.LoadDetails(ctx, updated, DetailsDiff)
+		require.NoError(t, err)
+		assert.Len(t, eng.calls, 2)
+	})
+
+	t.Run("pending pipeline is reloaded", func(t *testing.T) {
+		eng := &detailsEngine{pipeline: git.PipelineStatusRunning}
+		c := NewDiskCache(eng, t.TempDir())
+
+		_, err := c.LoadDetails(ctx, pr, DetailsPipeline|DetailsDiff)
+		require.NoError(t, err)
+
+		eng.pipeline = git.PipelineStatusSuccess
+		got, err := c.LoadDetails(ctx, pr, DetailsPipeline|DetailsDiff)
+		require.NoError(t, err)
+		assert.Equal(t, git.PipelineStatusSuccess, got.Pipeline.Status)
+		assert.Equal(t, 1, got.Diff.Files, "only the pipeline must be reloaded")
+
+		// settled pipeline is not reloaded anymore
+		_, err = c.LoadDetails(ctx, pr, DetailsPipeline|DetailsDiff)
+		require.NoError(t, err)
+
+		assert.Equal(t, []Details{DetailsPipeline | DetailsDiff, DetailsPipeline}, eng.calls)
+	})
+
+	t.Run("partial details are completed", func(t *testing.T) {
+		eng := &detailsEngine{pipeline: git.PipelineStatusSuccess}
+		c := NewDiskCache(eng, t.TempDir())
+
+		partial, err := c.LoadDetails(ctx, pr, DetailsApprovals)
+		require.NoError(t, err)
+		assert.Equal(t, []git.User{{Username: "alice"}}, partial.Approvals.By)
+		assert.Empty(t, partial.Threads, "details, that weren't requested, must not be loaded")
+
+		full, err := c.LoadDetails(ctx, pr, DetailsAll)
+		require.NoError(t, err)
+		assert.Equal(t, []git.User{{Username: "alice"}}, full.Approvals.By, "cached approvals must be kept")
+		assert.Len(t, full.Threads, 1)
+		assert.Equal(t, git.PipelineStatusSuccess, full.Pipeline.Status)
+		assert.Equal(t, 2, full.Diff.Files)
+
+		// the full details are cached, and the partial request is served from them
+		again, err := c.LoadDetails(ctx, pr, DetailsAll)
+		require.NoError(t, err)
+		assert.Equal(t, full, again)
+
+		part, err := c.LoadDetails(ctx, pr, DetailsDiscussions)
+		require.NoError(t, err)
+		assert.Len(t, part.Threads, 1)
+
+		assert.Equal(t, []Details{DetailsApprovals, DetailsAll &^ DetailsApprovals}, eng.calls)
+	})
+}
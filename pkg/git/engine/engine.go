@@ -6,34 +6,142 @@ import (
 	"context"
 	"github.com/Semior001/glmrl/pkg/git"
 	"github.com/Semior001/glmrl/pkg/misc"
+	"github.com/go-pkgz/requester"
+	"github.com/go-pkgz/requester/middleware"
+	"github.com/go-pkgz/requester/middleware/logger"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"io"
 	"log"
+	"net/http"
+	"sort"
+	"time"
 )
 
 // ListPRsRequest is a request to list pull requests.
 // Bools with pointers are used to specify whether to include (true) or exclude (false) pull requests with a
 // specific state. If a pointer is nil, pull requests with the corresponding state are not filtered.
 type ListPRsRequest struct {
-	State      git.State
+	State      git.State // empty means pull requests in all states, drafts included
 	Labels     misc.Filter[string]
 	Sort       misc.Sort
 	Pagination misc.Pagination
+
+	// Filters below narrow down the list at the engine's side, so that the
+	// details of pull requests, that would be filtered out anyway, are not
+	// loaded. Engines apply only those, supported by their APIs, so the
+	// caller must filter the results by these criteria as well.
+	Author       string    // username of the author
+	Reviewer     string    // username of the requested reviewer
+	Assignee     string    // username of the assignee
+	Project      string    // full path of the project
+	Group        string    // full path of the group (or the owner) of projects
+	Search       string    // text in the title or the description
+	TargetBranch string    // name of the target branch
+	CreatedAfter time.Time // zero means no limit
+	UpdatedAfter time.Time // zero means no limit
 }
 
+// Details is a set of details of a pull request, which are expensive to
+// load, as they require additional requests for each pull request.
+type Details uint
+
+// Details, that might be loaded on demand.
+const (
+	DetailsApprovals   Details = 1 << iota // approvals and approval rules
+	DetailsDiscussions                     // threads and the history of events
+	DetailsCommits                         // commits in the history of events
+	DetailsPipeline                        // status of the latest pipeline
+	DetailsDiff                            // diff stats
+
+	DetailsNone Details = 0
+	DetailsAll          = DetailsApprovals | DetailsDiscussions | DetailsCommits | DetailsPipeline | DetailsDiff
+)
+
+// Has returns true if the set contains all the given details.
+func (d Details) Has(other Details) bool { return d&other == other }
+
 //go:generate gowrap gen -g -p . -i Interface -t opentelemetry -o engine_trace_gen.go
 
 // Interface defines methods each git engine client should implement.
 type Interface interface {
-	// ListPullRequests lists pull requests.
+	// ListPullRequests lists pull requests without expensive details,
+	// engines might fill some of them, if their APIs return them anyway.
 	ListPullRequests(ctx context.Context, req ListPRsRequest) ([]git.PullRequest, error)
+	// LoadDetails loads the requested details of the pull request,
+	// listed by ListPullRequests. Unsupported details are left empty.
+	LoadDetails(ctx context.Context, pr git.PullRequest, details Details) (git.PullRequest, error)
 	// GetCurrentUser returns the current user.
 	GetCurrentUser(ctx context.Context) (git.User, error)
 	// Approve approves the pull request.
 	Approve(ctx context.Context, projectID string, number int) error
 }
 
+// SortPullRequests sorts pull requests in place by the given parameters.
+// It is used to order the results of engines, which APIs don't support
+// sorting, and to merge results of several engines.
+func SortPullRequests(prs []git.PullRequest, s misc.Sort) {
+	less := func(a, b git.PullRequest) bool {
+		switch s.By {
+		case misc.SortByTitle:
+			return a.Title < b.Title
+		case misc.SortByUpdatedAt:
+			return a.UpdatedAt.Before(b.UpdatedAt)
+		default:
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+	}
+
+	sort.SliceStable(prs, func(i, j int) bool {
+		if s.Order == misc.SortOrderAsc {
+			return less(prs[i], prs[j])
+		}
+		return less(prs[j], prs[i])
+	})
+}
+
+// newHTTPClient makes an HTTP client, that traces and logs requests, shared by all engines.
+// Requests of the client are made within the given limits.
+func newHTTPClient(version string, limits Limits) *http.Client {
+	rq := requester.New(
+		http.Client{
+			Transport: otelhttp.NewTransport(
+				middleware.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					req.Body = dumpBody(req.Context(), "request.body", req.Body)
+					resp, err := http.DefaultTransport.RoundTrip(req)
+					if err != nil {
+						return nil, err
+					}
+					resp.Body = dumpBody(req.Context(), "response.body", resp.Body)
+					return resp, nil
+				}),
+				otelhttp.WithMessageEvents(otelhttp.ReadEvents, otelhttp.WriteEvents),
+			),
+			Timeout: time.Minute,
+		},
+		logger.New(logger.Func(log.Printf), logger.Prefix("[DEBUG]"), logger.WithBody).Middleware,
+		middleware.Header("User-Agent", "glmrl "+version),
+		newLimiter(limits).Middleware,
+	)
+
+	return rq.Client()
+}
+
+// buildThread links the comments of a single discussion, ordered by
+// creation time, into a thread, where the first comment is the root.
+func buildThread(comments []git.Comment, resolved bool) git.Comment {
+	for idx := range comments {
+		comments[idx].Resolved = resolved
+	}
+
+	for idx := len(comments) - 2; idx >= 0; idx-- {
+		comments[idx].Child = &comments[idx+1]
+	}
+
+	return comments[0]
+}
+
 // dumpBody dumps the reader's content to span's attributes and makes a new reader from it.
 func dumpBody(ctx context.Context, key string, rd io.ReadCloser) io.ReadCloser {
 	span := trace.SpanFromContext(ctx)
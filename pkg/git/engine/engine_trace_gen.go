@@ -101,3 +101,27 @@ func (_d InterfaceWithTracing) ListPullRequests(ctx context.Context, req ListPRs
 	}()
 	return _d.Interface.ListPullRequests(ctx, req)
 }
+
+// LoadDetails implements Interface
+func (_d InterfaceWithTracing) LoadDetails(ctx context.Context, pr git.PullRequest, details Details) (p1 git.PullRequest, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "Interface.LoadDetails")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx":     ctx,
+				"pr":      pr,
+				"details": details}, map[string]interface{}{
+				"p1":  p1,
+				"err": err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.Interface.LoadDetails(ctx, pr, details)
+}
@@ -0,0 +1,507 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Semior001/glmrl/pkg/git"
+	"github.com/Semior001/glmrl/pkg/misc"
+	"github.com/samber/lo"
+	"go.opentelemetry.io/otel"
+	"golang.org/x/sync/errgroup"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// giteaConcurrency limits the number of concurrent requests per listing.
+const giteaConcurrency = 8
+
+// Gitea implements Interface for Gitea and Forgejo.
+type Gitea struct {
+	rest *restClient
+}
+
+// NewGitea returns a new Gitea service.
+func NewGitea(token, baseURL, version string, limits Limits) (*Gitea, error) {
+	if _, err := url.Parse(baseURL); err != nil {
+		return nil, fmt.Errorf("parse base url: %w", err)
+	}
+
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if !strings.HasSuffix(baseURL, "/api/v1") {
+		baseURL += "/api/v1"
+	}
+
+	return &Gitea{rest: &restClient{
+		cl:      newHTTPClient(version, limits),
+		baseURL: baseURL,
+		headers: map[string]string{"Authorization": "token " + token},
+	}}, nil
+}
+
+// ListPullRequests lists pull requests.
+func (g *Gitea) ListPullRequests(ctx context.Context, req ListPRsRequest) ([]git.PullRequest, error) {
+	q := url.Values{"type": {"pulls"}}
+	if req.Pagination.Page != 0 {
+		q.Set("page", strconv.Itoa(req.Pagination.Page))
+	}
+	if req.Pagination.PerPage != 0 {
+		q.Set("limit", strconv.Itoa(req.Pagination.PerPage))
+	}
+	if len(req.Labels.Include) > 0 {
+		q.Set("labels", strings.Join(req.Labels.Include, ","))
+	}
+	if req.Search != "" {
+		q.Set("q", req.Search)
+	}
+	if req.Group != "" {
+		q.Set("owner", req.Group)
+	}
+	if !req.UpdatedAfter.IsZero() {
+		q.Set("since", req.UpdatedAfter.Format(time.RFC3339))
+	}
+
+	switch req.State {
+	case git.StateOpen, git.StateDraft:
+		q.Set("state", "open")
+	case git.StateClosed, git.StateMerged:
+		q.Set("state", "closed")
+	default:
+		q.Set("state", "all")
+	}
+
+	var issues []giteaIssue
+	if _, err := g.rest.get(ctx, "repos/issues/search", q, &issues); err != nil {
+		return nil, fmt.Errorf("call api: %w", err)
+	}
+
+	// API doesn't support some filters, e.g. excluded labels or drafts, and sorting, they're
+	// applied by the service, the page is returned as is, so that the caller doesn't stop at
+	// a filtered out page, and sorting a single page would be in vain
+	result := make([]git.PullRequest, len(issues))
+	ewg, ctx := errgroup.WithContext(ctx)
+	ewg.SetLimit(giteaConcurrency)
+	for idx, issue := range issues {
+		idx, issue := idx, issue
+		ewg.Go(func() error {
+			ctx, span := otel.GetTracerProvider().Tracer("gitea").
+				Start(ctx, fmt.Sprintf("Gitea.loadPR(%s)", issue.HTMLURL))
+			defer span.End()
+
+			pr, err := g.loadPR(ctx, issue.Repository.FullName, issue.Number)
+			if err != nil {
+				return fmt.Errorf("load PR %s: %w", issue.HTMLURL, err)
+			}
+
+			result[idx] = pr
+			return nil
+		})
+	}
+
+	if err := ewg.Wait(); err != nil {
+		return nil, fmt.Errorf("wait for goroutines: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetCurrentUser returns the current user.
+func (g *Gitea) GetCurrentUser(ctx context.Context) (git.User, error) {
+	var u giteaUser
+	if _, err := g.rest.get(ctx, "user", nil, &u); err != nil {
+		return git.User{}, fmt.Errorf("call api to get current user: %w", err)
+	}
+	return g.transformUser(u), nil
+}
+
+// Approve approves a pull request.
+// Project ID is expected to be a full name of the repository, i.e. "owner/repo".
+func (g *Gitea) Approve(ctx context.Context, projectID string, number int) error {
+	path := fmt.Sprintf("repos/%s/pulls/%d/reviews", projectID, number)
+	if _, err := g.rest.post(ctx, path, map[string]string{"event": "APPROVED"}, nil); err != nil {
+		return fmt.Errorf("call api: %w", err)
+	}
+	return nil
+}
+
+func (g *Gitea) loadPR(ctx context.Context, repo string, number int) (git.PullRequest, error) {
+	var pull giteaPull
+	if _, err := g.rest.get(ctx, fmt.Sprintf("repos/%s/pulls/%d", repo, number), nil, &pull); err != nil {
+		return git.PullRequest{}, fmt.Errorf("call api to get pull request: %w", err)
+	}
+
+	return g.transformPull(pull), nil
+}
+
+// LoadDetails loads the requested details of the pull request.
+// Approvals and discussions are loaded together, as both are made of
+// reviews, while pipelines and commits are not supported.
+func (g *Gitea) LoadDetails(ctx context.Context, pr git.PullRequest, details Details) (git.PullRequest, error) {
+	if !details.Has(DetailsApprovals) && !details.Has(DetailsDiscussions) {
+		return pr, nil
+	}
+
+	repo, number := pr.Project.FullPath, pr.Number
+
+	var (
+		reviews  []giteaReview
+		comments []giteaReviewComment
+		required int
+	)
+
+	ewg, ctx := errgroup.WithContext(ctx)
+	ewg.Go(func() error {
+		var err error
+		if reviews, comments, err = g.listReviews(ctx, repo, number); err != nil {
+			return fmt.Errorf("list reviews: %w", err)
+		}
+		return nil
+	})
+	ewg.Go(func() error {
+		var err error
+		if required, err = g.requiredApprovals(ctx, repo, pr.TargetBranch); err != nil {
+			return fmt.Errorf("get required approvals: %w", err)
+		}
+		return nil
+	})
+
+	if err := ewg.Wait(); err != nil {
+		return git.PullRequest{}, fmt.Errorf("wait for goroutines: %w", err)
+	}
+
+	// only the latest meaningful review of each user matters
+	latest := map[string]giteaReview{}
+	for _, r := range reviews {
+		if r.Dismissed || (r.State != "APPROVED" && r.State != "REQUEST_CHANGES") {
+			continue
+		}
+		latest[r.User.Login] = r
+	}
+
+	officialApprovals, changesRequested := 0, false
+	for _, r := range reviews {
+		if latest[r.User.Login].ID != r.ID {
+			continue
+		}
+
+		switch r.State {
+		case "APPROVED":
+			pr.Approvals.By = append(pr.Approvals.By, g.transformUser(r.User))
+			if r.Official {
+				officialApprovals++
+			}
+		case "REQUEST_CHANGES":
+			changesRequested = changesRequested || r.Official
+		}
+	}
+
+	pr.Approvals.Required = required
+	pr.Approvals.SatisfiesRules = officialApprovals >= required && !changesRequested
+
+	pr.History = g.assembleHistory(reviews, comments)
+	pr.Threads = g.buildThreads(comments)
+
+	return pr, nil
+}
+
+// listReviews lists all reviews of the pull request alongside with their comments.
+func (g *Gitea) listReviews(ctx context.Context, repo string, number int) ([]giteaReview, []giteaReviewComment, error) {
+	reviews, err := misc.ListAll(1, func(page int) ([]giteaReview, error) {
+		var reviews []giteaReview
+		q := url.Values{"page": {strconv.Itoa(page)}, "limit": {"50"}}
+		if _, err := g.rest.get(ctx, fmt.Sprintf("repos/%s/pulls/%d/reviews", repo, number), q, &reviews); err != nil {
+			return nil, fmt.Errorf("call api: %w", err)
+		}
+		return reviews, nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("list reviews: %w", err)
+	}
+
+	comments := make([][]giteaReviewComment, len(reviews))
+	ewg, ctx := errgroup.WithContext(ctx)
+	ewg.SetLimit(giteaConcurrency)
+	for idx, r := range reviews {
+		idx, r := idx, r
+		if r.CommentsCount == 0 {
+			continue
+		}
+
+		ewg.Go(func() error {
+			path := fmt.Sprintf("repos/%s/pulls/%d/reviews/%d/comments", repo, number, r.ID)
+			if _, err := g.rest.get(ctx, path, nil, &comments[idx]); err != nil {
+				return fmt.Errorf("call api to get comments of review %d: %w", r.ID, err)
+			}
+			return nil
+		})
+	}
+
+	if err = ewg.Wait(); err != nil {
+		return nil, nil, fmt.Errorf("wait for goroutines: %w", err)
+	}
+
+	return reviews, lo.Flatten(comments), nil
+}
+
+// requiredApprovals returns the number of approvals required by the protection
+// of the given branch. Reading branch protections requires admin access to
+// the repository, so if there is no access, we consider there are no rules.
+func (g *Gitea) requiredApprovals(ctx context.Context, repo, branch string) (int, error) {
+	var protection struct {
+		RequiredApprovals int `json:"required_approvals"`
+	}
+
+	path := fmt.Sprintf("repos/%s/branch_protections/%s", repo, url.PathEscape(branch))
+	_, err := g.rest.get(ctx, path, nil, &protection)
+
+	var statusErr StatusError
+	if errors.As(err, &statusErr) && (statusErr.Code == http.StatusNotFound || statusErr.Code == http.StatusForbidden) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("call api: %w", err)
+	}
+
+	return protection.RequiredApprovals, nil
+}
+
+func (g *Gitea) assembleHistory(reviews []giteaReview, comments []giteaReviewComment) []git.Event {
+	var evs []git.Event
+
+	for _, r := range reviews {
+		ev := git.Event{
+			ID:        strconv.FormatInt(r.ID, 10),
+			Actor:     g.transformUser(r.User),
+			Timestamp: r.SubmittedAt,
+		}
+
+		switch r.State {
+		case "APPROVED":
+			ev.Type = git.EventTypeApproved
+		case "REQUEST_CHANGES":
+			ev.Type = git.EventTypeChangesRequested
+		default:
+			continue
+		}
+
+		evs = append(evs, ev)
+	}
+
+	sort.Slice(comments, func(i, j int) bool { return comments[i].CreatedAt.Before(comments[j].CreatedAt) })
+
+	// comments are grouped into conversations by their reviews and positions in the diff
+	firstInThread, lastInThread := map[string]giteaReviewComment{}, map[string]giteaReviewComment{}
+	for _, c := range comments {
+		key := g.threadKey(c)
+
+		_, replied := lastInThread[key]
+		lastInThread[key] = c
+		if !replied {
+			firstInThread[key] = c
+		}
+
+		evs = append(evs, git.Event{
+			ID:         strconv.FormatInt(c.ID, 10),
+			Actor:      g.transformUser(c.User),
+			Timestamp:  c.CreatedAt,
+			Type:       lo.Ternary(replied, git.EventTypeReplied, git.EventTypeCommented),
+			ObjectID:   strconv.FormatInt(firstInThread[key].ID, 10),
+			ObjectType: git.ObjectTypeComment,
+		})
+	}
+
+	for key, c := range lastInThread {
+		// the conversation is resolved on its first comment
+		first := firstInThread[key]
+		if first.Resolver == nil {
+			continue
+		}
+
+		// API doesn't provide the time of resolution, so we consider
+		// the thread to be resolved right after the last comment
+		evs = append(evs, git.Event{
+			ID:          fmt.Sprintf("%d!resolved", c.ID),
+			Actor:       g.transformUser(*first.Resolver),
+			Timestamp:   c.CreatedAt,
+			Type:        git.EventTypeThreadResolved,
+			Approximate: true,
+			ObjectID:    strconv.FormatInt(first.ID, 10),
+			ObjectType:  git.ObjectTypeComment,
+		})
+	}
+
+	// sort in ascending order, keeping resolutions after the last comment
+	sort.SliceStable(evs, func(i, j int) bool {
+		if evs[i].Timestamp.Equal(evs[j].Timestamp) {
+			return evs[i].Type != git.EventTypeThreadResolved && evs[j].Type == git.EventTypeThreadResolved
+		}
+		return evs[i].Timestamp.Before(evs[j].Timestamp)
+	})
+
+	return evs
+}
+
+// buildThreads groups the comments, sorted by creation time, into threads.
+// The ID of the first comment of the thread is used as the discussion ID.
+func (g *Gitea) buildThreads(comments []giteaReviewComment) []git.Comment {
+	var keys []string
+	byKey := map[string][]giteaReviewComment{}
+	for _, c := range comments {
+		key := g.threadKey(c)
+		if _, ok := byKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], c)
+	}
+
+	threads := make([]git.Comment, len(keys))
+	for idx, key := range keys {
+		cs := byKey[key]
+		threads[idx] = buildThread(misc.Map(cs, func(c giteaReviewComment) git.Comment {
+			return git.Comment{
+				DiscussionID: strconv.FormatInt(cs[0].ID, 10),
+				NoteID:       strconv.FormatInt(c.ID, 10),
+				Author:       g.transformUser(c.User),
+				Body:         c.Body,
+				File:         c.Path,
+				Line:         lo.Ternary(c.Position != 0, c.Position, c.OriginalPosition),
+				CreatedAt:    c.CreatedAt,
+			}
+		}), cs[0].Resolver != nil) // the conversation is resolved on its first comment
+	}
+
+	return threads
+}
+
+// threadKey returns the key of the conversation of the comment. Gitea doesn't
+// expose conversations in the API, but replies to a conversation are kept in
+// the review of its first comment, so that several conversations on the same
+// line are told apart by their reviews.
+func (g *Gitea) threadKey(c giteaReviewComment) string {
+	return fmt.Sprintf("%d:%s:%d", c.ReviewID, c.Path, lo.Ternary(c.Position != 0, c.Position, c.OriginalPosition))
+}
+
+func (g *Gitea) transformPull(pull giteaPull) git.PullRequest {
+	pr := git.PullRequest{
+		URL:    pull.HTMLURL,
+		Number: pull.Number,
+		Project: git.Project{
+			ID:       pull.Base.Repo.FullName,
+			URL:      pull.Base.Repo.HTMLURL,
+			Name:     pull.Base.Repo.Name,
+			FullPath: pull.Base.Repo.FullName,
+		},
+		Title:        pull.Title,
+		Body:         pull.Body,
+		Author:       g.transformUser(pull.User),
+		Labels:       lo.Map(pull.Labels, func(l giteaLabel, _ int) string { return l.Name }),
+		SourceBranch: pull.Head.Ref,
+		TargetBranch: pull.Base.Ref,
+		Assignees:    misc.Map(pull.Assignees, g.transformUser),
+		ClosedAt:     lo.FromPtr(lo.Ternary(pull.Merged, pull.MergedAt, pull.ClosedAt)),
+		CreatedAt:    pull.CreatedAt,
+		UpdatedAt:    pull.UpdatedAt,
+		Diff:         git.DiffStats{Files: pull.ChangedFiles, Additions: pull.Additions, Deletions: pull.Deletions},
+	}
+
+	pr.Approvals.RequestedFrom = misc.Map(pull.RequestedReviewers, g.transformUser)
+
+	// gitea reports only whether the pull request can be merged without conflicts
+	if pull.State == "open" {
+		pr.Mergeability = git.Mergeability{
+			HasConflicts: !pull.Mergeable,
+			Status:       lo.Ternary(pull.Mergeable, "mergeable", "conflict"),
+		}
+	}
+
+	switch {
+	case pull.Merged:
+		pr.State = git.StateMerged
+	case pull.State == "closed":
+		pr.State = git.StateClosed
+	case pull.Draft:
+		pr.State = git.StateDraft
+	case pull.State == "open":
+		pr.State = git.StateOpen
+	}
+
+	return pr
+}
+
+func (g *Gitea) transformUser(u giteaUser) git.User { return git.User{Username: u.Login} }
+
+type giteaUser struct {
+	Login string `json:"login"`
+}
+
+type giteaLabel struct {
+	Name string `json:"name"`
+}
+
+type giteaIssue struct {
+	Number     int    `json:"number"`
+	HTMLURL    string `json:"html_url"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+type giteaPull struct {
+	Number             int          `json:"number"`
+	HTMLURL            string       `json:"html_url"`
+	Title              string       `json:"title"`
+	Body               string       `json:"body"`
+	State              string       `json:"state"`
+	Draft              bool         `json:"draft"`
+	Merged             bool         `json:"merged"`
+	Mergeable          bool         `json:"mergeable"`
+	Additions          int          `json:"additions"`
+	Deletions          int          `json:"deletions"`
+	ChangedFiles       int          `json:"changed_files"`
+	User               giteaUser    `json:"user"`
+	Labels             []giteaLabel `json:"labels"`
+	Assignees          []giteaUser  `json:"assignees"`
+	RequestedReviewers []giteaUser  `json:"requested_reviewers"`
+	Head               giteaRef     `json:"head"`
+	Base               giteaRef     `json:"base"`
+	CreatedAt          time.Time    `json:"created_at"`
+	UpdatedAt          time.Time    `json:"updated_at"`
+	ClosedAt           *time.Time   `json:"closed_at"`
+	MergedAt           *time.Time   `json:"merged_at"`
+}
+
+type giteaRef struct {
+	Ref  string `json:"ref"`
+	Repo struct {
+		Name     string `json:"name"`
+		FullName string `json:"full_name"`
+		HTMLURL  string `json:"html_url"`
+	} `json:"repo"`
+}
+
+type giteaReview struct {
+	ID            int64     `json:"id"`
+	User          giteaUser `json:"user"`
+	State         string    `json:"state"`
+	Official      bool      `json:"official"`
+	Dismissed     bool      `json:"dismissed"`
+	CommentsCount int       `json:"comments_count"`
+	SubmittedAt   time.Time `json:"submitted_at"`
+}
+
+type giteaReviewComment struct {
+	ID               int64      `json:"id"`
+	ReviewID         int64      `json:"pull_request_review_id"`
+	User             giteaUser  `json:"user"`
+	Body             string     `json:"body"`
+	Path             string     `json:"path"`
+	Position         int        `json:"position"`
+	OriginalPosition int        `json:"original_position"`
+	Resolver         *giteaUser `json:"resolver"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"context"
+	"github.com/Semior001/glmrl/pkg/git"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGitea_LoadDetails_threadResolution(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/repos/owner/repo/pulls/1/reviews", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "1" {
+			_, _ = w.Write([]byte(`[]`))
+			return
+		}
+		_, _ = w.Write([]byte(`[
+			{"id": 1, "user": {"login": "alice"}, "state": "COMMENT", "comments_count": 4,
+			 "submitted_at": "2023-01-01T00:00:00Z"},
+			{"id": 2, "user": {"login": "bob"}, "state": "COMMENT", "comments_count": 1,
+			 "submitted_at": "2023-01-02T00:00:00Z"}
+		]`))
+	})
+	mux.HandleFunc("/api/v1/repos/owner/repo/pulls/1/reviews/1/comments", func(w http.ResponseWriter, r *http.Request) {
+		// replies are kept in the review of the first comment of the conversation,
+		// gitea reports the resolver only on the first comment of the conversation,
+		// replies must not affect the resolution
+		_, _ = w.Write([]byte(`[
+			{"id": 11, "pull_request_review_id": 1, "user": {"login": "alice"}, "body": "resolved, then replied",
+			 "path": "a.go", "position": 10, "resolver": {"login": "bob"}, "created_at": "2023-01-01T00:00:00Z"},
+			{"id": 12, "pull_request_review_id": 1, "user": {"login": "alice"}, "body": "unresolved",
+			 "path": "b.go", "position": 5, "created_at": "2023-01-01T00:00:01Z"},
+			{"id": 13, "pull_request_review_id": 1, "user": {"login": "bob"}, "body": "reply",
+			 "path": "a.go", "position": 10, "created_at": "2023-01-02T00:00:00Z"},
+			{"id": 14, "pull_request_review_id": 1, "user": {"login": "bob"}, "body": "reply",
+			 "path": "b.go", "position": 5, "resolver": {"login": "bob"}, "created_at": "2023-01-02T00:00:01Z"}
+		]`))
+	})
+	mux.HandleFunc("/api/v1/repos/owner/repo/pulls/1/reviews/2/comments", func(w http.ResponseWriter, r *http.Request) {
+		// a new conversation on the same line
+		_, _ = w.Write([]byte(`[
+			{"id": 21, "pull_request_review_id": 2, "user": {"login": "bob"}, "body": "another thread",
+			 "path": "a.go", "position": 10, "created_at": "2023-01-02T00:00:02Z"}
+		]`))
+	})
+	mux.HandleFunc("/api/v1/repos/owner/repo/branch_protections/main", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	g, err := NewGitea("token", srv.URL, "test", Limits{})
+	require.NoError(t, err)
+
+	pr, err := g.LoadDetails(context.Background(), git.PullRequest{
+		Project:      git.Project{ID: "owner/repo", FullPath: "owner/repo"},
+		Number:       1,
+		TargetBranch: "main",
+	}, DetailsAll)
+	require.NoError(t, err)
+
+	require.Len(t, pr.Threads, 3)
+	resolved := lo.SliceToMap(pr.Threads, func(c git.Comment) (string, bool) { return c.DiscussionID, c.Resolved })
+	assert.Equal(t, map[string]bool{"11": true, "12": false, "21": false}, resolved)
+
+	for _, thread := range pr.Threads[:2] {
+		require.NotNil(t, thread.Child)
+		assert.Equal(t, thread.Resolved, thread.Child.Resolved, "all comments share the resolution of the thread")
+		assert.Nil(t, thread.Child.Child, "conversations on the same line must not be merged")
+	}
+
+	threadOf := lo.SliceToMap(lo.Filter(pr.History, func(ev git.Event, _ int) bool {
+		return ev.Type == git.EventTypeCommented || ev.Type == git.EventTypeReplied
+	}), func(ev git.Event) (string, string) { return ev.ID, ev.ObjectID })
+	assert.Equal(t, map[string]string{"11": "11", "12": "12", "13": "11", "14": "12", "21": "21"}, threadOf)
+
+	resolutions := lo.Filter(pr.History, func(ev git.Event, _ int) bool { return ev.Type == git.EventTypeThreadResolved })
+	require.Len(t, resolutions, 1)
+	assert.Equal(t, "11", resolutions[0].ObjectID)
+	assert.Equal(t, "bob", resolutions[0].Actor.Username)
+	assert.Equal(t, "13!resolved", resolutions[0].ID, "resolution goes after the last comment")
+	assert.True(t, resolutions[0].Approximate)
+}
@@ -0,0 +1,622 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"github.com/Semior001/glmrl/pkg/git"
+	"github.com/Semior001/glmrl/pkg/misc"
+	"github.com/samber/lo"
+	"go.opentelemetry.io/otel"
+	"golang.org/x/sync/errgroup"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultGithubURL is the URL of the public Github API.
+	DefaultGithubURL = "https://api.github.com"
+	// githubConcurrency limits the number of concurrent requests per listing.
+	githubConcurrency = 8
+)
+
+// Github implements Interface for Github.
+type Github struct {
+	rest   *restClient
+	gql    *restClient
+	owners []string
+}
+
+// NewGithub returns a new Github service.
+// Owners limit the search of pull requests to the given users or organizations,
+// if none specified, only pull requests that involve the current user are listed.
+func NewGithub(token, baseURL, version string, owners []string, limits Limits) (*Github, error) {
+	if baseURL == "" {
+		baseURL = DefaultGithubURL
+	}
+
+	if _, err := url.Parse(baseURL); err != nil {
+		return nil, fmt.Errorf("parse base url: %w", err)
+	}
+
+	headers := map[string]string{
+		"Authorization":        "Bearer " + token,
+		"X-GitHub-Api-Version": "2022-11-28",
+	}
+
+	cl := newHTTPClient(version, limits)
+
+	return &Github{
+		rest:   &restClient{cl: cl, baseURL: baseURL, headers: headers},
+		gql:    &restClient{cl: cl, baseURL: githubGraphQLURL(baseURL), headers: headers},
+		owners: owners,
+	}, nil
+}
+
+// githubGraphQLURL returns the GraphQL endpoint for the given REST API URL.
+// Github Enterprise serves REST API at /api/v3 and GraphQL at /api/graphql,
+// while the public Github serves GraphQL at /graphql.
+func githubGraphQLURL(baseURL string) string {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if strings.HasSuffix(baseURL, "/api/v3") {
+		return strings.TrimSuffix(baseURL, "/v3") + "/graphql"
+	}
+	return baseURL + "/graphql"
+}
+
+// ListPullRequests lists pull requests.
+func (g *Github) ListPullRequests(ctx context.Context, req ListPRsRequest) ([]git.PullRequest, error) {
+	q := url.Values{"q": {g.searchQuery(req)}}
+	if req.Pagination.Page != 0 {
+		q.Set("page", strconv.Itoa(req.Pagination.Page))
+	}
+	if req.Pagination.PerPage != 0 {
+		q.Set("per_page", strconv.Itoa(req.Pagination.PerPage))
+	}
+
+	// search API doesn't support sorting by title
+	switch req.Sort.By {
+	case misc.SortByCreatedAt:
+		q.Set("sort", "created")
+	case misc.SortByUpdatedAt:
+		q.Set("sort", "updated")
+	}
+	if req.Sort.Order != "" {
+		q.Set("order", string(req.Sort.Order))
+	}
+
+	var resp struct {
+		Items []githubIssue `json:"items"`
+	}
+
+	if _, err := g.rest.get(ctx, "search/issues", q, &resp); err != nil {
+		return nil, fmt.Errorf("call api: %w", err)
+	}
+
+	result := make([]git.PullRequest, len(resp.Items))
+	ewg, ctx := errgroup.WithContext(ctx)
+	ewg.SetLimit(githubConcurrency)
+	for idx, issue := range resp.Items {
+		idx, issue := idx, issue
+		ewg.Go(func() error {
+			ctx, span := otel.GetTracerProvider().Tracer("github").
+				Start(ctx, fmt.Sprintf("Github.loadPR(%s)", issue.HTMLURL))
+			defer span.End()
+
+			pr, err := g.loadPR(ctx, issue)
+			if err != nil {
+				return fmt.Errorf("load PR %s: %w", issue.HTMLURL, err)
+			}
+
+			result[idx] = pr
+			return nil
+		})
+	}
+
+	if err := ewg.Wait(); err != nil {
+		return nil, fmt.Errorf("wait for goroutines: %w", err)
+	}
+
+	return result, nil
+}
+
+// searchQuery builds a search query for the issues search API.
+func (g *Github) searchQuery(req ListPRsRequest) string {
+	terms := []string{"is:pr", "archived:false"}
+
+	switch req.State {
+	case git.StateOpen:
+		terms = append(terms, "is:open")
+	case git.StateClosed:
+		terms = append(terms, "is:closed", "is:unmerged")
+	case git.StateMerged:
+		terms = append(terms, "is:merged")
+	case git.StateDraft:
+		terms = append(terms, "is:open")
+	}
+
+	if req.State != "" {
+		terms = append(terms, lo.Ternary(req.State == git.StateDraft, "draft:true", "draft:false"))
+	}
+
+	for _, l := range req.Labels.Include {
+		terms = append(terms, fmt.Sprintf("label:%q", l))
+	}
+
+	for _, l := range req.Labels.Exclude {
+		terms = append(terms, fmt.Sprintf("-label:%q", l))
+	}
+
+	for _, q := range []struct{ qualifier, value string }{
+		{"author", req.Author},
+		{"review-requested", req.Reviewer},
+		{"assignee", req.Assignee},
+		{"repo", req.Project},
+		{"base", req.TargetBranch},
+	} {
+		if q.value != "" {
+			terms = append(terms, fmt.Sprintf("%s:%q", q.qualifier, q.value))
+		}
+	}
+
+	if !req.CreatedAfter.IsZero() {
+		terms = append(terms, "created:>="+req.CreatedAfter.UTC().Format(time.RFC3339))
+	}
+
+	if !req.UpdatedAfter.IsZero() {
+		terms = append(terms, "updated:>="+req.UpdatedAfter.UTC().Format(time.RFC3339))
+	}
+
+	if req.Search != "" {
+		terms = append(terms, fmt.Sprintf("%q", req.Search), "in:title,body")
+	}
+
+	// several user qualifiers are joined by OR, so the requested
+	// group replaces the configured owners to narrow down the search
+	if req.Group != "" {
+		terms = append(terms, "user:"+req.Group)
+		return strings.Join(terms, " ")
+	}
+
+	if len(g.owners) == 0 {
+		terms = append(terms, "involves:@me")
+	}
+
+	for _, o := range g.owners {
+		terms = append(terms, "user:"+o)
+	}
+
+	return strings.Join(terms, " ")
+}
+
+// GetCurrentUser returns the current user.
+func (g *Github) GetCurrentUser(ctx context.Context) (git.User, error) {
+	var u githubUser
+	if _, err := g.rest.get(ctx, "user", nil, &u); err != nil {
+		return git.User{}, fmt.Errorf("call api to get current user: %w", err)
+	}
+	return g.transformUser(u), nil
+}
+
+// Approve approves a pull request.
+// Project ID is expected to be a full name of the repository, i.e. "owner/repo".
+func (g *Github) Approve(ctx context.Context, projectID string, number int) error {
+	path := fmt.Sprintf("repos/%s/pulls/%d/reviews", projectID, number)
+	if _, err := g.rest.post(ctx, path, map[string]string{"event": "APPROVE"}, nil); err != nil {
+		return fmt.Errorf("call api: %w", err)
+	}
+	return nil
+}
+
+func (g *Github) loadPR(ctx context.Context, issue githubIssue) (git.PullRequest, error) {
+	repo := strings.TrimPrefix(issue.RepositoryURL, strings.TrimSuffix(g.rest.baseURL, "/")+"/repos/")
+
+	var pull githubPull
+	if _, err := g.rest.get(ctx, fmt.Sprintf("repos/%s/pulls/%d", repo, issue.Number), nil, &pull); err != nil {
+		return git.PullRequest{}, fmt.Errorf("call api to get pull request: %w", err)
+	}
+
+	return g.transformPull(pull), nil
+}
+
+// LoadDetails loads the requested details of the pull request.
+// Approvals and discussions are loaded together, as both are made of
+// reviews, while pipelines and commits are not supported.
+func (g *Github) LoadDetails(ctx context.Context, pr git.PullRequest, details Details) (git.PullRequest, error) {
+	if !details.Has(DetailsApprovals) && !details.Has(DetailsDiscussions) {
+		return pr, nil
+	}
+
+	repo := pr.Project.FullPath
+
+	var (
+		reviews []githubReview
+		threads githubThreads
+	)
+
+	ewg, ctx := errgroup.WithContext(ctx)
+	ewg.Go(func() error {
+		var err error
+		if reviews, err = g.listReviews(ctx, repo, pr.Number); err != nil {
+			return fmt.Errorf("list reviews: %w", err)
+		}
+		return nil
+	})
+	ewg.Go(func() error {
+		var err error
+		if threads, err = g.listThreads(ctx, repo, pr.Number); err != nil {
+			return fmt.Errorf("list review threads: %w", err)
+		}
+		return nil
+	})
+
+	if err := ewg.Wait(); err != nil {
+		return git.PullRequest{}, fmt.Errorf("wait for goroutines: %w", err)
+	}
+
+	// only the latest meaningful review of each user matters
+	latest := map[string]githubReview{}
+	for _, r := range reviews {
+		if r.State == "COMMENTED" || r.State == "PENDING" {
+			continue
+		}
+		latest[r.User.Login] = r
+	}
+
+	for _, r := range reviews {
+		if r.State == "APPROVED" && latest[r.User.Login].ID == r.ID {
+			pr.Approvals.By = append(pr.Approvals.By, g.transformUser(r.User))
+		}
+	}
+
+	// review decision is empty when the repository doesn't require reviews
+	pr.Approvals.SatisfiesRules = threads.ReviewDecision == "" || threads.ReviewDecision == "APPROVED"
+
+	pr.History = g.assembleHistory(reviews, threads.Nodes)
+	pr.Threads = g.buildThreads(threads.Nodes)
+
+	return pr, nil
+}
+
+func (g *Github) listReviews(ctx context.Context, repo string, number int) ([]githubReview, error) {
+	return misc.ListAll(1, func(page int) ([]githubReview, error) {
+		var reviews []githubReview
+		q := url.Values{"page": {strconv.Itoa(page)}, "per_page": {"100"}}
+		if _, err := g.rest.get(ctx, fmt.Sprintf("repos/%s/pulls/%d/reviews", repo, number), q, &reviews); err != nil {
+			return nil, fmt.Errorf("call api: %w", err)
+		}
+		return reviews, nil
+	})
+}
+
+const githubThreadsQuery = `query($owner: String!, $name: String!, $number: Int!, $after: String) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      reviewDecision
+      reviewThreads(first: 100, after: $after) {
+        pageInfo { hasNextPage endCursor }
+        nodes {
+          id
+          isResolved
+          resolvedBy { login }
+          path
+          line
+          originalLine
+          comments(first: 100) {
+            pageInfo { hasNextPage endCursor }
+            nodes { databaseId author { login } body createdAt }
+          }
+        }
+      }
+    }
+  }
+}`
+
+func (g *Github) listThreads(ctx context.Context, repo string, number int) (githubThreads, error) {
+	owner, name, _ := strings.Cut(repo, "/")
+
+	var (
+		result githubThreads
+		after  *string
+	)
+
+	for {
+		var data struct {
+			Repository struct {
+				PullRequest struct {
+					ReviewDecision string `json:"reviewDecision"`
+					ReviewThreads  struct {
+						PageInfo githubPageInfo `json:"pageInfo"`
+						Nodes    []githubThread `json:"nodes"`
+					} `json:"reviewThreads"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		}
+
+		vars := map[string]any{"owner": owner, "name": name, "number": number, "after": after}
+		if err := g.gql.graphql(ctx, githubThreadsQuery, vars, &data); err != nil {
+			return githubThreads{}, err
+		}
+
+		pr := data.Repository.PullRequest
+		result.ReviewDecision = pr.ReviewDecision
+		result.Nodes = append(result.Nodes, pr.ReviewThreads.Nodes...)
+
+		if !pr.ReviewThreads.PageInfo.HasNextPage {
+			break
+		}
+
+		after = lo.ToPtr(pr.ReviewThreads.PageInfo.EndCursor)
+	}
+
+	// threads are listed only with the first page of their comments
+	for idx, th := range result.Nodes {
+		if !th.Comments.PageInfo.HasNextPage {
+			continue
+		}
+
+		rest, err := g.listThreadComments(ctx, th.ID, th.Comments.PageInfo.EndCursor)
+		if err != nil {
+			return githubThreads{}, fmt.Errorf("list comments of thread %s: %w", th.ID, err)
+		}
+
+		result.Nodes[idx].Comments.Nodes = append(result.Nodes[idx].Comments.Nodes, rest...)
+	}
+
+	return result, nil
+}
+
+const githubThreadCommentsQuery = `query($id: ID!, $after: String) {
+  node(id: $id) {
+    ... on PullRequestReviewThread {
+      comments(first: 100, after: $after) {
+        pageInfo { hasNextPage endCursor }
+        nodes { databaseId author { login } body createdAt }
+      }
+    }
+  }
+}`
+
+// listThreadComments lists the comments of the thread, starting after the given cursor.
+func (g *Github) listThreadComments(ctx context.Context, threadID, after string) ([]githubComment, error) {
+	var result []githubComment
+
+	for {
+		var data struct {
+			Node struct {
+				Comments githubComments `json:"comments"`
+			} `json:"node"`
+		}
+
+		vars := map[string]any{"id": threadID, "after": after}
+		if err := g.gql.graphql(ctx, githubThreadCommentsQuery, vars, &data); err != nil {
+			return nil, err
+		}
+
+		result = append(result, data.Node.Comments.Nodes...)
+
+		if !data.Node.Comments.PageInfo.HasNextPage {
+			return result, nil
+		}
+
+		after = data.Node.Comments.PageInfo.EndCursor
+	}
+}
+
+func (g *Github) assembleHistory(reviews []githubReview, threads []githubThread) []git.Event {
+	var evs []git.Event
+
+	for _, r := range reviews {
+		ev := git.Event{
+			ID:        strconv.FormatInt(r.ID, 10),
+			Actor:     g.transformUser(r.User),
+			Timestamp: r.SubmittedAt,
+		}
+
+		switch r.State {
+		case "APPROVED":
+			ev.Type = git.EventTypeApproved
+		case "CHANGES_REQUESTED":
+			ev.Type = git.EventTypeChangesRequested
+		default:
+			continue
+		}
+
+		evs = append(evs, ev)
+	}
+
+	for _, th := range threads {
+		for idx, c := range th.Comments.Nodes {
+			evs = append(evs, git.Event{
+				ID:         strconv.FormatInt(c.DatabaseID, 10),
+				Actor:      g.transformUser(c.Author),
+				Timestamp:  c.CreatedAt,
+				Type:       lo.Ternary(idx == 0, git.EventTypeCommented, git.EventTypeReplied),
+				ObjectID:   th.ID,
+				ObjectType: git.ObjectTypeComment,
+			})
+		}
+
+		if !th.IsResolved || len(th.Comments.Nodes) == 0 {
+			continue
+		}
+
+		// API doesn't provide the time of resolution, so we consider
+		// the thread to be resolved right after the last comment
+		evs = append(evs, git.Event{
+			ID:          fmt.Sprintf("%s!resolved", th.ID),
+			Actor:       g.transformUser(lo.FromPtr(th.ResolvedBy)),
+			Timestamp:   th.Comments.Nodes[len(th.Comments.Nodes)-1].CreatedAt,
+			Type:        git.EventTypeThreadResolved,
+			Approximate: true,
+			ObjectID:    th.ID,
+			ObjectType:  git.ObjectTypeComment,
+		})
+	}
+
+	// sort in ascending order, keeping resolutions after the last comment
+	sort.SliceStable(evs, func(i, j int) bool { return evs[i].Timestamp.Before(evs[j].Timestamp) })
+
+	return evs
+}
+
+func (g *Github) buildThreads(threads []githubThread) []git.Comment {
+	var result []git.Comment
+	for _, th := range threads {
+		if len(th.Comments.Nodes) == 0 {
+			continue
+		}
+
+		line := lo.FromPtr(lo.Ternary(th.Line != nil, th.Line, th.OriginalLine))
+		comments := make([]git.Comment, len(th.Comments.Nodes))
+		for idx, c := range th.Comments.Nodes {
+			comments[idx] = git.Comment{
+				DiscussionID: th.ID,
+				NoteID:       strconv.FormatInt(c.DatabaseID, 10),
+				Author:       g.transformUser(c.Author),
+				Body:         c.Body,
+				File:         th.Path,
+				Line:         line,
+				CreatedAt:    c.CreatedAt,
+			}
+		}
+
+		result = append(result, buildThread(comments, th.IsResolved))
+	}
+	return result
+}
+
+func (g *Github) transformPull(pull githubPull) git.PullRequest {
+	pr := git.PullRequest{
+		URL:    pull.HTMLURL,
+		Number: pull.Number,
+		Project: git.Project{
+			ID:       pull.Base.Repo.FullName,
+			URL:      pull.Base.Repo.HTMLURL,
+			Name:     pull.Base.Repo.Name,
+			FullPath: pull.Base.Repo.FullName,
+		},
+		Title:        pull.Title,
+		Body:         pull.Body,
+		Author:       g.transformUser(pull.User),
+		Labels:       lo.Map(pull.Labels, func(l githubLabel, _ int) string { return l.Name }),
+		SourceBranch: pull.Head.Ref,
+		TargetBranch: pull.Base.Ref,
+		Assignees:    misc.Map(pull.Assignees, g.transformUser),
+		ClosedAt:     lo.FromPtr(lo.Ternary(pull.MergedAt != nil, pull.MergedAt, pull.ClosedAt)),
+		CreatedAt:    pull.CreatedAt,
+		UpdatedAt:    pull.UpdatedAt,
+		Diff:         git.DiffStats{Files: pull.ChangedFiles, Additions: pull.Additions, Deletions: pull.Deletions},
+	}
+
+	pr.Approvals.RequestedFrom = misc.Map(pull.RequestedReviewers, g.transformUser)
+
+	// "dirty" means conflicts, "behind" means the head branch is out of date
+	pr.Mergeability = git.Mergeability{
+		HasConflicts: pull.MergeableState == "dirty",
+		NeedsRebase:  pull.MergeableState == "behind",
+		Status:       pull.MergeableState,
+	}
+
+	switch {
+	case pull.MergedAt != nil:
+		pr.State = git.StateMerged
+	case pull.State == "closed":
+		pr.State = git.StateClosed
+	case pull.Draft:
+		pr.State = git.StateDraft
+	case pull.State == "open":
+		pr.State = git.StateOpen
+	}
+
+	return pr
+}
+
+func (g *Github) transformUser(u githubUser) git.User { return git.User{Username: u.Login} }
+
+type githubUser struct {
+	Login string `json:"login"`
+}
+
+type githubLabel struct {
+	Name string `json:"name"`
+}
+
+type githubIssue struct {
+	Number        int    `json:"number"`
+	HTMLURL       string `json:"html_url"`
+	RepositoryURL string `json:"repository_url"`
+}
+
+type githubPull struct {
+	Number             int           `json:"number"`
+	HTMLURL            string        `json:"html_url"`
+	Title              string        `json:"title"`
+	Body               string        `json:"body"`
+	State              string        `json:"state"`
+	Draft              bool          `json:"draft"`
+	User               githubUser    `json:"user"`
+	Labels             []githubLabel `json:"labels"`
+	Assignees          []githubUser  `json:"assignees"`
+	RequestedReviewers []githubUser  `json:"requested_reviewers"`
+	Head               githubRef     `json:"head"`
+	Base               githubRef     `json:"base"`
+	CreatedAt          time.Time     `json:"created_at"`
+	UpdatedAt          time.Time     `json:"updated_at"`
+	ClosedAt           *time.Time    `json:"closed_at"`
+	MergedAt           *time.Time    `json:"merged_at"`
+	MergeableState     string        `json:"mergeable_state"`
+	Additions          int           `json:"additions"`
+	Deletions          int           `json:"deletions"`
+	ChangedFiles       int           `json:"changed_files"`
+}
+
+type githubRef struct {
+	Ref  string `json:"ref"`
+	Repo struct {
+		Name     string `json:"name"`
+		FullName string `json:"full_name"`
+		HTMLURL  string `json:"html_url"`
+	} `json:"repo"`
+}
+
+type githubReview struct {
+	ID          int64      `json:"id"`
+	User        githubUser `json:"user"`
+	State       string     `json:"state"`
+	SubmittedAt time.Time  `json:"submitted_at"`
+}
+
+type githubThreads struct {
+	ReviewDecision string
+	Nodes          []githubThread
+}
+
+type githubThread struct {
+	ID           string         `json:"id"`
+	IsResolved   bool           `json:"isResolved"`
+	ResolvedBy   *githubUser    `json:"resolvedBy"`
+	Path         string         `json:"path"`
+	Line         *int           `json:"line"`         // nil if the line is outdated
+	OriginalLine *int           `json:"originalLine"` // line at the moment of commenting
+	Comments     githubComments `json:"comments"`
+}
+
+type githubComments struct {
+	PageInfo githubPageInfo  `json:"pageInfo"`
+	Nodes    []githubComment `json:"nodes"`
+}
+
+type githubComment struct {
+	DatabaseID int64      `json:"databaseId"`
+	Author     githubUser `json:"author"`
+	Body       string     `json:"body"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+type githubPageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
@@ -0,0 +1,173 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Semior001/glmrl/pkg/git"
+	"github.com/Semior001/glmrl/pkg/misc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGithub_ListPullRequests(t *testing.T) {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.HandleFunc("/search/issues", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, `is:pr archived:false is:open draft:false label:"bug" author:"alice" user:org`,
+			r.URL.Query().Get("q"))
+		assert.Equal(t, "2", r.URL.Query().Get("page"))
+		_, _ = fmt.Fprintf(w, `{"items": [{"number": 1, "html_url": "https://github.com/org/repo/pull/1",
+			"repository_url": "%s/repos/org/repo"}]}`, srv.URL)
+	})
+	mux.HandleFunc("/repos/org/repo/pulls/1", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{
+			"number": 1, "html_url": "https://github.com/org/repo/pull/1", "title": "feat: something",
+			"state": "open", "draft": false, "user": {"login": "alice"}, "labels": [{"name": "bug"}],
+			"requested_reviewers": [{"login": "bob"}], "mergeable_state": "dirty",
+			"head": {"ref": "feature"},
+			"base": {"ref": "master", "repo": {"name": "repo", "full_name": "org/repo"}},
+			"additions": 10, "deletions": 5, "changed_files": 2
+		}`))
+	})
+
+	g, err := NewGithub("token", srv.URL, "test", []string{"org"}, Limits{})
+	require.NoError(t, err)
+
+	prs, err := g.ListPullRequests(context.Background(), ListPRsRequest{
+		State:      git.StateOpen,
+		Labels:     misc.Filter[string]{Include: []string{"bug"}},
+		Author:     "alice",
+		Pagination: misc.Pagination{Page: 2},
+	})
+	require.NoError(t, err)
+	require.Len(t, prs, 1)
+
+	pr := prs[0]
+	assert.Equal(t, "org/repo", pr.Project.FullPath)
+	assert.Equal(t, git.StateOpen, pr.State)
+	assert.Equal(t, "alice", pr.Author.Username)
+	assert.Equal(t, []string{"bug"}, pr.Labels)
+	assert.Equal(t, []git.User{{Username: "bob"}}, pr.Approvals.RequestedFrom)
+	assert.True(t, pr.Mergeability.HasConflicts)
+	assert.Equal(t, git.DiffStats{Files: 2, Additions: 10, Deletions: 5}, pr.Diff)
+}
+
+func TestGithub_LoadDetails(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/org/repo/pulls/1/reviews", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "1" {
+			_, _ = w.Write([]byte(`[]`))
+			return
+		}
+		_, _ = w.Write([]byte(`[
+			{"id": 1, "user": {"login": "bob"}, "state": "APPROVED", "submitted_at": "2023-01-01T00:00:00Z"},
+			{"id": 2, "user": {"login": "carol"}, "state": "APPROVED", "submitted_at": "2023-01-01T00:00:00Z"},
+			{"id": 3, "user": {"login": "carol"}, "state": "CHANGES_REQUESTED", "submitted_at": "2023-01-02T00:00:00Z"}
+		]`))
+	})
+	mux.HandleFunc("/graphql", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		switch {
+		case strings.Contains(req.Query, "reviewThreads") && req.Variables["after"] == nil:
+			// the first page of threads, the first thread has more comments
+			_, _ = w.Write([]byte(`{"data": {"repository": {"pullRequest": {
+				"reviewDecision": "CHANGES_REQUESTED",
+				"reviewThreads": {"pageInfo": {"hasNextPage": true, "endCursor": "t1"}, "nodes": [
+					{"id": "T1", "isResolved": true, "resolvedBy": {"login": "alice"}, "path": "a.go", "line": 10,
+					 "comments": {"pageInfo": {"hasNextPage": true, "endCursor": "c1"}, "nodes": [
+						{"databaseId": 1, "author": {"login": "bob"}, "body": "c1", "createdAt": "2023-01-01T00:00:01Z"}
+					 ]}}
+				]}
+			}}}}`))
+		case strings.Contains(req.Query, "reviewThreads") && req.Variables["after"] == "t1":
+			_, _ = w.Write([]byte(`{"data": {"repository": {"pullRequest": {
+				"reviewDecision": "CHANGES_REQUESTED",
+				"reviewThreads": {"pageInfo": {"hasNextPage": false, "endCursor": "t2"}, "nodes": [
+					{"id": "T2", "isResolved": false, "path": "b.go", "originalLine": 5,
+					 "comments": {"pageInfo": {"hasNextPage": false}, "nodes": [
+						{"databaseId": 4, "author": {"login": "carol"}, "body": "c4", "createdAt": "2023-01-01T00:00:04Z"}
+					 ]}}
+				]}
+			}}}}`))
+		case strings.Contains(req.Query, "node(id: $id)") && req.Variables["after"] == "c1":
+			assert.Equal(t, "T1", req.Variables["id"])
+			_, _ = w.Write([]byte(`{"data": {"node": {"comments": {
+				"pageInfo": {"hasNextPage": true, "endCursor": "c2"}, "nodes": [
+					{"databaseId": 2, "author": {"login": "alice"}, "body": "c2", "createdAt": "2023-01-01T00:00:02Z"}
+				]
+			}}}}`))
+		case strings.Contains(req.Query, "node(id: $id)") && req.Variables["after"] == "c2":
+			_, _ = w.Write([]byte(`{"data": {"node": {"comments": {
+				"pageInfo": {"hasNextPage": false, "endCursor": "c3"}, "nodes": [
+					{"databaseId": 3, "author": {"login": "bob"}, "body": "c3", "createdAt": "2023-01-01T00:00:03Z"}
+				]
+			}}}}`))
+		default:
+			t.Errorf("unexpected query with variables %v", req.Variables)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	g, err := NewGithub("token", srv.URL, "test", nil, Limits{})
+	require.NoError(t, err)
+
+	pr, err := g.LoadDetails(context.Background(), git.PullRequest{
+		Project: git.Project{ID: "org/repo", FullPath: "org/repo"},
+		Number:  1,
+	}, DetailsAll)
+	require.NoError(t, err)
+
+	// carol's approval was overridden by her request for changes
+	assert.Equal(t, []git.User{{Username: "bob"}}, pr.Approvals.By)
+	assert.False(t, pr.Approvals.SatisfiesRules)
+
+	require.Len(t, pr.Threads, 2)
+
+	var bodies []string
+	for c := &pr.Threads[0]; c != nil; c = c.Child {
+		assert.Equal(t, "a.go", c.File)
+		assert.Equal(t, 10, c.Line)
+		assert.True(t, c.Resolved)
+		bodies = append(bodies, c.Body)
+	}
+	assert.Equal(t, []string{"c1", "c2", "c3"}, bodies, "all pages of comments must be listed")
+
+	assert.Equal(t, "b.go", pr.Threads[1].File)
+	assert.Equal(t, 5, pr.Threads[1].Line, "original line is used for outdated threads")
+	assert.False(t, pr.Threads[1].Resolved)
+
+	types := make([]string, len(pr.History))
+	for idx, ev := range pr.History {
+		types[idx] = fmt.Sprintf("%s:%s", ev.Type, ev.ID)
+	}
+	assert.Equal(t, []string{
+		fmt.Sprintf("%s:1", git.EventTypeApproved),
+		fmt.Sprintf("%s:2", git.EventTypeApproved),
+		fmt.Sprintf("%s:1", git.EventTypeCommented),
+		fmt.Sprintf("%s:2", git.EventTypeReplied),
+		fmt.Sprintf("%s:3", git.EventTypeReplied),
+		fmt.Sprintf("%s:T1!resolved", git.EventTypeThreadResolved),
+		fmt.Sprintf("%s:4", git.EventTypeCommented),
+		fmt.Sprintf("%s:3", git.EventTypeChangesRequested),
+	}, types)
+
+	for _, ev := range pr.History {
+		assert.Equal(t, ev.Type == git.EventTypeThreadResolved, ev.Approximate,
+			"only the time of resolution is approximate: %s", ev.ID)
+	}
+}
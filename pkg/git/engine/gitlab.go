@@ -6,54 +6,43 @@ import (
 	"github.com/Semior001/glmrl/pkg/git"
 	"github.com/Semior001/glmrl/pkg/misc"
 	cache "github.com/go-pkgz/expirable-cache/v2"
-	"github.com/go-pkgz/requester"
-	"github.com/go-pkgz/requester/middleware"
-	"github.com/go-pkgz/requester/middleware/logger"
 	"github.com/samber/lo"
 	gl "github.com/xanzy/go-gitlab"
-	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel"
 	"golang.org/x/sync/errgroup"
-	"log"
 	"net/http"
+	"net/url"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 )
 
+const (
+	// gitlabPerPage is the maximum page size, allowed by the API.
+	gitlabPerPage = 100
+	// gitlabConcurrency limits the number of concurrent requests per listing.
+	gitlabConcurrency = 8
+)
+
 // Gitlab implements Interface for Gitlab.
 type Gitlab struct {
 	cl            *gl.Client
+	gql           *restClient
 	projectsCache cache.Cache[int, git.Project]
 }
 
 // NewGitlab returns a new Gitlab service.
-func NewGitlab(token, baseURL, version string) (*Gitlab, error) {
-	rq := requester.New(
-		http.Client{
-			Transport: otelhttp.NewTransport(
-				middleware.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
-					req.Body = dumpBody(req.Context(), "request.body", req.Body)
-					resp, err := http.DefaultTransport.RoundTrip(req)
-					if err != nil {
-						return nil, err
-					}
-					resp.Body = dumpBody(req.Context(), "response.body", resp.Body)
-					return resp, nil
-				}),
-				otelhttp.WithMessageEvents(otelhttp.ReadEvents, otelhttp.WriteEvents),
-			),
-			Timeout: time.Minute,
-		},
-		logger.New(logger.Func(log.Printf), logger.Prefix("[DEBUG]"), logger.WithBody).Middleware,
-		middleware.Header("User-Agent", "glmrl "+version),
-	)
+func NewGitlab(token, baseURL, version string, limits Limits) (*Gitlab, error) {
+	httpCl := newHTTPClient(version, limits)
 
 	cl, err := gl.NewClient(
 		token,
 		gl.WithBaseURL(baseURL),
-		gl.WithHTTPClient(rq.Client()),
+		gl.WithHTTPClient(httpCl),
+		// retries are made by the limiter of the http client
+		gl.WithCustomRetryMax(0),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("init gitlab client: %w", err)
@@ -61,12 +50,25 @@ func NewGitlab(token, baseURL, version string) (*Gitlab, error) {
 
 	return &Gitlab{
 		cl: cl,
+		gql: &restClient{
+			cl:      httpCl,
+			baseURL: gitlabGraphQLURL(cl.BaseURL()),
+			headers: map[string]string{"Authorization": "Bearer " + token},
+		},
 		projectsCache: cache.NewCache[int, git.Project]().
 			WithLRU().
 			WithMaxKeys(100),
 	}, nil
 }
 
+// gitlabGraphQLURL returns the GraphQL endpoint for the given REST API URL,
+// REST API is served at /api/v4 and GraphQL at /api/graphql.
+func gitlabGraphQLURL(restURL *url.URL) string {
+	u := *restURL
+	u.Path = strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), "/v4") + "/graphql"
+	return u.String()
+}
+
 // ListPullRequests lists pull requests.
 func (g *Gitlab) ListPullRequests(ctx context.Context, req ListPRsRequest) ([]git.PullRequest, error) {
 	opts := &gl.ListMergeRequestsOptions{
@@ -75,9 +77,26 @@ func (g *Gitlab) ListPullRequests(ctx context.Context, req ListPRsRequest) ([]gi
 		NotLabels:   lo.Ternary(len(req.Labels.Exclude) > 0, (*gl.Labels)(&req.Labels.Exclude), nil),
 		OrderBy:     lo.Ternary(req.Sort.By != "", lo.ToPtr(string(req.Sort.By)), nil),
 		Sort:        lo.Ternary(req.Sort.Order != "", lo.ToPtr(string(req.Sort.Order)), nil),
-		Draft:       lo.Ternary(req.State == git.StateDraft, lo.ToPtr(true), nil),
-		WIP:         lo.Ternary(req.State == git.StateDraft, lo.ToPtr("yes"), lo.ToPtr("no")),
 		ListOptions: gl.ListOptions{Page: req.Pagination.Page, PerPage: req.Pagination.PerPage},
+
+		AuthorUsername:   lo.Ternary(req.Author != "", &req.Author, nil),
+		ReviewerUsername: lo.Ternary(req.Reviewer != "", &req.Reviewer, nil),
+		Search:           lo.Ternary(req.Search != "", &req.Search, nil),
+		TargetBranch:     lo.Ternary(req.TargetBranch != "", &req.TargetBranch, nil),
+		CreatedAfter:     lo.Ternary(!req.CreatedAfter.IsZero(), &req.CreatedAfter, nil),
+		UpdatedAfter:     lo.Ternary(!req.UpdatedAfter.IsZero(), &req.UpdatedAfter, nil),
+	}
+
+	// API filters assignees only by their IDs
+	if req.Assignee != "" {
+		id, found, err := g.userID(ctx, req.Assignee)
+		if err != nil {
+			return nil, fmt.Errorf("get id of assignee %q: %w", req.Assignee, err)
+		}
+		if !found {
+			return nil, nil
+		}
+		opts.AssigneeID = gl.AssigneeID(id)
 	}
 
 	// try to reduce the filtering to one of these states, instead of listing all and then filtering
@@ -91,13 +110,23 @@ func (g *Gitlab) ListPullRequests(ctx context.Context, req ListPRsRequest) ([]gi
 		opts.State = lo.ToPtr("merged")
 	}
 
-	mrs, _, err := g.cl.MergeRequests.ListMergeRequests(opts, gl.WithContext(ctx))
+	switch req.State {
+	case "":
+		// drafts are listed as well
+	case git.StateDraft:
+		opts.Draft, opts.WIP = lo.ToPtr(true), lo.ToPtr("yes")
+	default:
+		opts.WIP = lo.ToPtr("no")
+	}
+
+	mrs, err := g.listMergeRequests(ctx, req, opts)
 	if err != nil {
 		return nil, fmt.Errorf("call api: %w", err)
 	}
 
 	result := make([]git.PullRequest, len(mrs))
 	ewg, ctx := errgroup.WithContext(ctx)
+	ewg.SetLimit(gitlabConcurrency)
 	for idx, mr := range mrs {
 		idx, mr := idx, mr
 		ewg.Go(func() error {
@@ -122,6 +151,48 @@ func (g *Gitlab) ListPullRequests(ctx context.Context, req ListPRsRequest) ([]gi
 	return result, nil
 }
 
+// listMergeRequests lists merge requests of the requested project or group, if any,
+// and all merge requests, visible to the user, otherwise. Scoped endpoints accept
+// the same parameters as the global one, so the options are reused for them.
+func (g *Gitlab) listMergeRequests(ctx context.Context, req ListPRsRequest, opts *gl.ListMergeRequestsOptions) ([]*gl.MergeRequest, error) {
+	var path string
+	switch {
+	case req.Project != "":
+		path = fmt.Sprintf("projects/%s/merge_requests", url.PathEscape(req.Project))
+	case req.Group != "":
+		path = fmt.Sprintf("groups/%s/merge_requests", url.PathEscape(req.Group))
+	default:
+		mrs, _, err := g.cl.MergeRequests.ListMergeRequests(opts, gl.WithContext(ctx))
+		return mrs, err
+	}
+
+	httpReq, err := g.cl.NewRequest(http.MethodGet, path, opts, []gl.RequestOptionFunc{gl.WithContext(ctx)})
+	if err != nil {
+		return nil, fmt.Errorf("make request: %w", err)
+	}
+
+	var mrs []*gl.MergeRequest
+	if _, err = g.cl.Do(httpReq, &mrs); err != nil {
+		return nil, err
+	}
+
+	return mrs, nil
+}
+
+// userID returns the ID of the user with the given username.
+func (g *Gitlab) userID(ctx context.Context, username string) (id int, found bool, err error) {
+	users, _, err := g.cl.Users.ListUsers(&gl.ListUsersOptions{Username: &username}, gl.WithContext(ctx))
+	if err != nil {
+		return 0, false, fmt.Errorf("call api: %w", err)
+	}
+
+	if len(users) == 0 {
+		return 0, false, nil
+	}
+
+	return users[0].ID, true, nil
+}
+
 // GetCurrentUser returns the current user.
 func (g *Gitlab) GetCurrentUser(ctx context.Context) (git.User, error) {
 	u, _, err := g.cl.Users.CurrentUser(gl.WithContext(ctx))
@@ -133,124 +204,408 @@ func (g *Gitlab) GetCurrentUser(ctx context.Context) (git.User, error) {
 
 func (g *Gitlab) loadPR(ctx context.Context, mr *gl.MergeRequest) (pr git.PullRequest, err error) {
 	pr = g.transformMergeRequest(mr)
+	if pr.Project, err = g.getProject(ctx, mr.ProjectID); err != nil {
+		return git.PullRequest{}, fmt.Errorf("get project %d: %w", mr.ProjectID, err)
+	}
+	return pr, nil
+}
+
+// LoadDetails loads the requested details of the merge request.
+func (g *Gitlab) LoadDetails(ctx context.Context, pr git.PullRequest, details Details) (git.PullRequest, error) {
+	pid, err := strconv.Atoi(pr.Project.ID)
+	if err != nil {
+		return git.PullRequest{}, fmt.Errorf("parse project id %q: %w", pr.Project.ID, err)
+	}
 
 	ewg, ctx := errgroup.WithContext(ctx)
-	ewg.Go(func() error {
-		if pr.Project, err = g.getProject(ctx, mr.ProjectID); err != nil {
-			return fmt.Errorf("get project %d: %w", mr.ProjectID, err)
-		}
-		return nil
-	})
-	ewg.Go(func() error {
-		approvals, _, err := g.cl.MergeRequests.GetMergeRequestApprovals(mr.ProjectID, mr.IID, nil, gl.WithContext(ctx))
-		if err != nil {
-			return fmt.Errorf("call api to get MR approvals: %w", err)
-		}
+	if details.Has(DetailsApprovals) {
+		ewg.Go(func() error {
+			approvals, _, err := g.cl.MergeRequests.GetMergeRequestApprovals(pid, pr.Number, nil, gl.WithContext(ctx))
+			if err != nil {
+				return fmt.Errorf("call api to get MR approvals: %w", err)
+			}
 
-		pr.Approvals.By = misc.Map(approvals.ApprovedBy, func(u *gl.MergeRequestApproverUser) git.User { return g.transformUser(u.User) })
-		pr.Approvals.SatisfiesRules = approvals.Approved
-		pr.Approvals.Required = approvals.ApprovalsRequired
-		return nil
-	})
-	ewg.Go(func() error {
-		if pr.History, err = g.assembleHistory(ctx, mr.ProjectID, mr.IID); err != nil {
-			return fmt.Errorf("assemble history: %w", err)
-		}
-		pr.Threads = g.buildThreads(pr.History)
-		return nil
-	})
+			pr.Approvals.By = misc.Map(approvals.ApprovedBy, func(u *gl.MergeRequestApproverUser) git.User { return g.transformUser(u.User) })
+			pr.Approvals.SatisfiesRules = approvals.Approved
+			pr.Approvals.Required = approvals.ApprovalsRequired
+			return nil
+		})
+	}
+
+	if details.Has(DetailsDiff) {
+		ewg.Go(func() error {
+			var err error
+			if pr.Diff, err = g.loadDiffStats(ctx, pr.Project.FullPath, pr.Number); err != nil {
+				return fmt.Errorf("load diff stats: %w", err)
+			}
+			return nil
+		})
+	}
+
+	var history, commits []git.Event
+	if details.Has(DetailsDiscussions) {
+		ewg.Go(func() error {
+			var err error
+			if pr.Threads, history, err = g.loadDiscussions(ctx, pid, pr.Number); err != nil {
+				return fmt.Errorf("load discussions: %w", err)
+			}
+			return nil
+		})
+	}
+
+	if details.Has(DetailsPipeline) {
+		ewg.Go(func() error {
+			var err error
+			if pr.Pipeline, err = g.loadPipeline(ctx, pid, pr.Number); err != nil {
+				return fmt.Errorf("load pipeline: %w", err)
+			}
+			return nil
+		})
+	}
+
+	if details.Has(DetailsCommits) {
+		ewg.Go(func() error {
+			var err error
+			if commits, err = g.loadPushes(ctx, pid, pr.Number); err != nil {
+				return fmt.Errorf("load pushes: %w", err)
+			}
+			return nil
+		})
+	}
 
 	if err = ewg.Wait(); err != nil {
 		return git.PullRequest{}, fmt.Errorf("wait for goroutines: %w", err)
 	}
 
+	if details.Has(DetailsCommits) {
+		history = attributePushes(history, commits)
+	}
+
+	pr.History = append(append(pr.History, history...), commits...)
+	sort.SliceStable(pr.History, func(i, j int) bool { return pr.History[i].Timestamp.Before(pr.History[j].Timestamp) })
+
 	return pr, nil
 }
 
-func (g *Gitlab) assembleHistory(ctx context.Context, pid, iid int) ([]git.Event, error) {
-	evSet := map[git.Event]struct{}{}
-	rootThreads := map[string]struct{}{}
+const gitlabDiffStatsQuery = `query($path: ID!, $iid: String!) {
+  project(fullPath: $path) {
+    mergeRequest(iid: $iid) {
+      diffStatsSummary { additions deletions fileCount }
+    }
+  }
+}`
+
+// loadDiffStats loads the numbers of changed files and lines of the merge request.
+// REST API provides only the diffs, which are truncated for large changes,
+// while GraphQL API provides the exact totals at once.
+func (g *Gitlab) loadDiffStats(ctx context.Context, projectPath string, iid int) (git.DiffStats, error) {
+	var data struct {
+		Project struct {
+			MergeRequest struct {
+				DiffStatsSummary struct {
+					Additions int `json:"additions"`
+					Deletions int `json:"deletions"`
+					FileCount int `json:"fileCount"`
+				} `json:"diffStatsSummary"`
+			} `json:"mergeRequest"`
+		} `json:"project"`
+	}
+
+	vars := map[string]any{"path": projectPath, "iid": strconv.Itoa(iid)}
+	if err := g.gql.graphql(ctx, gitlabDiffStatsQuery, vars, &data); err != nil {
+		return git.DiffStats{}, err
+	}
+
+	summary := data.Project.MergeRequest.DiffStatsSummary
+	return git.DiffStats{Files: summary.FileCount, Additions: summary.Additions, Deletions: summary.Deletions}, nil
+}
 
-	notes, _, err := g.cl.Notes.ListMergeRequestNotes(pid, iid, nil, gl.WithContext(ctx))
+// loadPipeline loads the head pipeline of the merge request with the names
+// of failed jobs, if the pipeline has failed.
+func (g *Gitlab) loadPipeline(ctx context.Context, pid, iid int) (git.Pipeline, error) {
+	// head pipeline is provided only for a single merge request
+	mr, _, err := g.cl.MergeRequests.GetMergeRequest(pid, iid, nil, gl.WithContext(ctx))
 	if err != nil {
-		return nil, fmt.Errorf("call api to get MR notes: %w", err)
+		return git.Pipeline{}, fmt.Errorf("call api to get MR: %w", err)
 	}
 
-	sort.Slice(notes, func(i, j int) bool { return notes[i].CreatedAt.Before(*notes[j].CreatedAt) })
+	if mr.HeadPipeline == nil {
+		return git.Pipeline{}, nil
+	}
 
-	for _, note := range notes {
-		evs, transformed := g.transformNote(rootThreads, note)
-		if !transformed {
-			continue
+	p := git.Pipeline{
+		Status:     g.transformPipelineStatus(mr.HeadPipeline.Status),
+		URL:        mr.HeadPipeline.WebURL,
+		FinishedAt: lo.FromPtr(mr.HeadPipeline.FinishedAt),
+	}
+
+	if p.Status != git.PipelineStatusFailed {
+		return p, nil
+	}
+
+	jobs, err := listAllPages(ctx, func(ctx context.Context, opts gl.ListOptions) ([]*gl.Job, *gl.Response, error) {
+		return g.cl.Jobs.ListPipelineJobs(pid, mr.HeadPipeline.ID, &gl.ListJobsOptions{
+			ListOptions: opts,
+			Scope:       &[]gl.BuildStateValue{gl.Failed},
+		}, gl.WithContext(ctx))
+	})
+	if err != nil {
+		return git.Pipeline{}, fmt.Errorf("call api to get failed jobs of pipeline %d: %w", mr.HeadPipeline.ID, err)
+	}
+
+	for _, job := range jobs {
+		if !job.AllowFailure {
+			p.FailedJobs = append(p.FailedJobs, job.Name)
 		}
+	}
+
+	return p, nil
+}
 
-		for _, ev := range evs {
-			evSet[ev] = struct{}{}
-			if ev.Type == git.EventTypeCommented {
-				rootThreads[ev.ObjectID] = struct{}{}
+func (g *Gitlab) transformPipelineStatus(status string) git.PipelineStatus {
+	switch status {
+	case "created", "waiting_for_resource", "preparing", "pending", "scheduled":
+		return git.PipelineStatusPending
+	case "running":
+		return git.PipelineStatusRunning
+	case "success":
+		return git.PipelineStatusSuccess
+	case "failed":
+		return git.PipelineStatusFailed
+	case "canceled":
+		return git.PipelineStatusCanceled
+	case "skipped":
+		return git.PipelineStatusSkipped
+	case "manual":
+		return git.PipelineStatusManual
+	default:
+		return git.PipelineStatusNone
+	}
+}
+
+// loadPushes loads pushes of commits to the merge request as events. Each push
+// makes a new version of the diff, the time of its creation is the time of the
+// push, unlike the dates of commits, which are kept on rebases and cherry-picks.
+// API doesn't provide the user, who pushed the commits, see attributePushes.
+func (g *Gitlab) loadPushes(ctx context.Context, pid, iid int) ([]git.Event, error) {
+	versions, err := listAllPages(ctx, func(ctx context.Context, opts gl.ListOptions) ([]*gl.MergeRequestDiffVersion, *gl.Response, error) {
+		return g.cl.MergeRequests.GetMergeRequestDiffVersions(pid, iid,
+			(*gl.GetMergeRequestDiffVersionsOptions)(&opts), gl.WithContext(ctx))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("call api to get MR diff versions: %w", err)
+	}
+
+	return misc.Map(versions, func(v *gl.MergeRequestDiffVersion) git.Event {
+		return git.Event{
+			ID:         fmt.Sprintf("version-%d", v.ID),
+			Timestamp:  lo.FromPtr(v.CreatedAt),
+			Type:       git.EventTypePushed,
+			ObjectID:   v.HeadCommitSHA,
+			ObjectType: git.ObjectTypeCommit,
+		}
+	}), nil
+}
+
+// pushNoteWindow is the maximal difference between the times of a diff version
+// and the system note about the same push.
+const pushNoteWindow = time.Minute
+
+// attributePushes sets the actors of pushes, loaded from diff versions, from
+// the system notes about the same pushes, and removes the pushes of notes
+// from the history, so each push is reported once. Each note is matched with
+// the closest version within pushNoteWindow.
+func attributePushes(history, pushes []git.Event) []git.Event {
+	used := make([]bool, len(pushes))
+	return lo.Filter(history, func(ev git.Event, _ int) bool {
+		if ev.Type != git.EventTypePushed {
+			return true
+		}
+
+		closest := -1
+		for idx, push := range pushes {
+			diff := ev.Timestamp.Sub(push.Timestamp).Abs()
+			if used[idx] || diff > pushNoteWindow {
+				continue
 			}
+			if closest == -1 || diff < ev.Timestamp.Sub(pushes[closest].Timestamp).Abs() {
+				closest = idx
+			}
+		}
+
+		if closest != -1 {
+			used[closest] = true
+			pushes[closest].Actor = ev.Actor
 		}
+		return false
+	})
+}
+
+// loadDiscussions loads threads of the merge request and assembles
+// the history of events out of them.
+func (g *Gitlab) loadDiscussions(ctx context.Context, pid, iid int) ([]git.Comment, []git.Event, error) {
+	discussions, err := listAllPages(ctx, func(ctx context.Context, opts gl.ListOptions) ([]*gl.Discussion, *gl.Response, error) {
+		return g.cl.Discussions.ListMergeRequestDiscussions(pid, iid,
+			(*gl.ListMergeRequestDiscussionsOptions)(&opts), gl.WithContext(ctx))
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("call api to get MR discussions: %w", err)
 	}
 
-	var evs []git.Event
-	for ev := range evSet {
-		evs = append(evs, ev)
+	var (
+		threads []git.Comment
+		evs     []git.Event
+	)
+
+	for _, d := range discussions {
+		if len(d.Notes) == 0 {
+			continue
+		}
+
+		if d.Notes[0].System {
+			for _, note := range d.Notes {
+				if ev, ok := g.transformSystemNote(note); ok {
+					evs = append(evs, ev)
+				}
+			}
+			continue
+		}
+
+		// individual notes are not resolvable, thus they're not threads
+		if !d.Notes[0].Resolvable {
+			continue
+		}
+
+		notes := d.Notes
+		sort.SliceStable(notes, func(i, j int) bool { return notes[i].CreatedAt.Before(*notes[j].CreatedAt) })
+
+		comments := misc.Map(notes, func(note *gl.Note) git.Comment { return g.transformNote(d.ID, note) })
+		for idx, c := range comments {
+			evs = append(evs, git.Event{
+				ID:         c.NoteID,
+				Actor:      c.Author,
+				Timestamp:  c.CreatedAt,
+				Type:       lo.Ternary(idx == 0, git.EventTypeCommented, git.EventTypeReplied),
+				ObjectID:   d.ID,
+				ObjectType: git.ObjectTypeComment,
+			})
+		}
+
+		last := notes[len(notes)-1]
+		if last.Resolved {
+			evs = append(evs, git.Event{
+				ID:         fmt.Sprintf("%s!resolved", d.ID),
+				Actor:      g.transformUser(&gl.BasicUser{Username: last.ResolvedBy.Username}),
+				Timestamp:  lo.FromPtr(last.ResolvedAt),
+				Type:       git.EventTypeThreadResolved,
+				ObjectID:   d.ID,
+				ObjectType: git.ObjectTypeComment,
+			})
+		}
+
+		threads = append(threads, buildThread(comments, last.Resolved))
 	}
 
 	// sort in ascending order
-	sort.Slice(evs, func(i, j int) bool { return evs[i].Timestamp.Before(evs[j].Timestamp) })
+	sort.SliceStable(evs, func(i, j int) bool { return evs[i].Timestamp.Before(evs[j].Timestamp) })
 
-	return evs, nil
+	return threads, evs, nil
 }
 
-func (g *Gitlab) threadPos(note *gl.Note) string {
-	if note.Position == nil {
-		return ""
+// listAllPages lists the first page to find out the total number of pages
+// from the response headers, and then lists the rest of them concurrently.
+// API omits the total for large collections, in this case pages are listed
+// one by one, following the link to the next page.
+func listAllPages[T any](
+	ctx context.Context,
+	listFn func(ctx context.Context, opts gl.ListOptions) ([]T, *gl.Response, error),
+) ([]T, error) {
+	first, resp, err := listFn(ctx, gl.ListOptions{Page: 1, PerPage: gitlabPerPage})
+	if err != nil {
+		return nil, misc.ErrAtPage{Page: 1, Err: err}
+	}
+
+	if resp.TotalPages == 0 {
+		result := first
+		for next := resp.NextPage; next != 0; next = resp.NextPage {
+			var items []T
+			if items, resp, err = listFn(ctx, gl.ListOptions{Page: next, PerPage: gitlabPerPage}); err != nil {
+				return nil, misc.ErrAtPage{Page: next, Err: err}
+			}
+			result = append(result, items...)
+		}
+		return result, nil
+	}
+
+	pages := make([][]T, resp.TotalPages)
+	pages[0] = first
+
+	ewg, ctx := errgroup.WithContext(ctx)
+	ewg.SetLimit(gitlabConcurrency)
+	for page := 2; page <= resp.TotalPages; page++ {
+		page := page
+		ewg.Go(func() error {
+			items, _, err := listFn(ctx, gl.ListOptions{Page: page, PerPage: gitlabPerPage})
+			if err != nil {
+				return misc.ErrAtPage{Page: page, Err: err}
+			}
+			pages[page-1] = items
+			return nil
+		})
+	}
+
+	if err = ewg.Wait(); err != nil {
+		return nil, err
 	}
 
-	return fmt.Sprintf("%s:%d", note.Position.NewPath, note.Position.NewLine)
+	return lo.Flatten(pages), nil
 }
 
-func (g *Gitlab) transformNote(rootThreads map[string]struct{}, note *gl.Note) (events []git.Event, transformed bool) {
-	ev := git.Event{ID: strconv.Itoa(note.ID), Timestamp: lo.FromPtr(note.CreatedAt)}
-	ev.Actor = g.transformUser(&gl.BasicUser{Username: note.Author.Username})
-	if note.System {
-		ev.Actor = git.SystemUser
+// pushNoteRe matches the system note about new commits, e.g. "added 3 commits".
+var pushNoteRe = regexp.MustCompile(`^added \d+ commits?`)
+
+// transformSystemNote makes an event out of the system note, if the note
+// describes a change in approvals, or a push to the source branch.
+func (g *Gitlab) transformSystemNote(note *gl.Note) (git.Event, bool) {
+	ev := git.Event{
+		ID:        strconv.Itoa(note.ID),
+		Actor:     g.transformUser(&gl.BasicUser{Username: note.Author.Username}),
+		Timestamp: lo.FromPtr(note.CreatedAt),
 	}
 
+	// "unapproved" contains "approved", so it must be checked first
 	switch {
-	case strings.Contains(note.Body, "approved this merge request"):
-		ev.Type = git.EventTypeApproved
 	case strings.Contains(note.Body, "unapproved this merge request"):
 		ev.Type = git.EventTypeUnapproved
+	case strings.Contains(note.Body, "approved this merge request"):
+		ev.Type = git.EventTypeApproved
+	case pushNoteRe.MatchString(note.Body):
+		ev.Type = git.EventTypePushed
+	default:
+		return git.Event{}, false
 	}
 
-	if !note.Resolvable {
-		return nil, false
-	}
-
-	ev.Type = git.EventTypeCommented
-	ev.ObjectType = git.ObjectTypeComment
-	ev.ObjectID = g.threadPos(note)
+	return ev, true
+}
 
-	if _, ok := rootThreads[g.threadPos(note)]; ok {
-		ev.Type = git.EventTypeReplied
+func (g *Gitlab) transformNote(discussionID string, note *gl.Note) git.Comment {
+	c := git.Comment{
+		DiscussionID: discussionID,
+		NoteID:       strconv.Itoa(note.ID),
+		Author:       g.transformUser(&gl.BasicUser{Username: note.Author.Username}),
+		Body:         note.Body,
+		CreatedAt:    lo.FromPtr(note.CreatedAt),
 	}
 
-	if note.Resolved {
-		resEv := git.Event{
-			ID:         fmt.Sprintf("%s!resolved", ev.ID),
-			Actor:      g.transformUser(&gl.BasicUser{Username: note.ResolvedBy.Username}),
-			Timestamp:  lo.FromPtr(note.ResolvedAt),
-			Type:       git.EventTypeThreadResolved,
-			ObjectID:   g.threadPos(note),
-			ObjectType: git.ObjectTypeComment,
+	if note.Position != nil {
+		// removed lines have only the old position
+		c.File, c.Line = note.Position.NewPath, note.Position.NewLine
+		if c.Line == 0 {
+			c.File, c.Line = note.Position.OldPath, note.Position.OldLine
 		}
-
-		return []git.Event{ev, resEv}, true
 	}
 
-	return []git.Event{ev}, true
+	return c
 }
 
 func (g *Gitlab) getProject(ctx context.Context, pid int) (git.Project, error) {
@@ -291,10 +646,18 @@ func (g *Gitlab) transformMergeRequest(mr *gl.MergeRequest) git.PullRequest {
 		TargetBranch: mr.TargetBranch,
 		Assignees:    misc.Map(mr.Assignees, g.transformUser),
 		CreatedAt:    lo.FromPtr(mr.CreatedAt),
+		UpdatedAt:    lo.FromPtr(mr.UpdatedAt),
 	}
 
 	pr.Approvals.RequestedFrom = misc.Map(mr.Reviewers, g.transformUser)
 
+	pr.Mergeability = git.Mergeability{
+		HasConflicts:         mr.HasConflicts || mr.DetailedMergeStatus == "conflict",
+		NeedsRebase:          mr.DetailedMergeStatus == "need_rebase",
+		BlockedByDiscussions: !mr.BlockingDiscussionsResolved || mr.DetailedMergeStatus == "discussions_not_resolved",
+		Status:               mr.DetailedMergeStatus,
+	}
+
 	switch {
 	case mr.Draft || mr.WorkInProgress:
 		pr.State = git.StateDraft
@@ -311,42 +674,6 @@ func (g *Gitlab) transformMergeRequest(mr *gl.MergeRequest) git.PullRequest {
 
 func (g *Gitlab) transformUser(u *gl.BasicUser) git.User { return git.User{Username: u.Username} }
 
-func (g *Gitlab) buildThreads(history []git.Event) []git.Comment {
-	threads := map[string]*git.Comment{}
-	for _, ev := range history {
-		switch ev.Type {
-		case git.EventTypeCommented:
-			threads[ev.ObjectID] = &git.Comment{Author: ev.Actor, CreatedAt: ev.Timestamp}
-		case git.EventTypeReplied:
-			thread, ok := threads[ev.ObjectID]
-			if !ok {
-				log.Printf("[WARN] thread %q not found", ev.ObjectID)
-				continue
-			}
-
-			for thread.Child != nil {
-				thread = thread.Child
-			}
-
-			thread.Child = &git.Comment{Author: ev.Actor, CreatedAt: ev.Timestamp}
-		case git.EventTypeThreadResolved:
-			thread, ok := threads[ev.ObjectID]
-			if !ok {
-				log.Printf("[WARN] thread %q not found", ev.ObjectID)
-				continue
-			}
-
-			thread.Resolved = true
-			for thread.Child != nil {
-				thread = thread.Child
-				thread.Resolved = true
-			}
-		}
-	}
-
-	return lo.Map(lo.Values(threads), func(c *git.Comment, _ int) git.Comment { return *c })
-}
-
 // Approve approves a pull request.
 func (g *Gitlab) Approve(ctx context.Context, projectID string, number int) error {
 	if _, _, err := g.cl.MergeRequestApprovals.ApproveMergeRequest(projectID, number, nil, gl.WithContext(ctx)); err != nil {
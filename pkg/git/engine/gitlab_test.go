@@ -0,0 +1,200 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Semior001/glmrl/pkg/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGitlab_loadDiscussions(t *testing.T) {
+	const pages = 20
+
+	tests := []struct {
+		name       string
+		totalPages bool // whether the server reports the total number of pages
+	}{
+		{name: "total pages are reported", totalPages: true},
+		{name: "only the next page is reported", totalPages: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inFlight, maxInFlight atomic.Int32
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/api/v4/projects/1/merge_requests/2/discussions", r.URL.Path)
+
+				n := inFlight.Add(1)
+				defer inFlight.Add(-1)
+				for m := maxInFlight.Load(); n > m && !maxInFlight.CompareAndSwap(m, n); m = maxInFlight.Load() {
+				}
+
+				// let concurrent requests overlap
+				time.Sleep(10 * time.Millisecond)
+
+				page, err := strconv.Atoi(r.URL.Query().Get("page"))
+				require.NoError(t, err)
+
+				if tt.totalPages {
+					w.Header().Set("X-Total-Pages", strconv.Itoa(pages))
+				}
+				if page < pages {
+					w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = fmt.Fprintf(w, `[
+					{"id": "d%[1]d", "notes": [
+						{"id": %[1]d1, "body": "comment", "author": {"username": "alice"},
+						 "created_at": "2023-01-01T00:00:00Z", "resolvable": true},
+						{"id": %[1]d2, "body": "reply", "author": {"username": "bob"},
+						 "created_at": "2023-01-02T00:00:00Z", "resolvable": true}
+					]},
+					{"id": "s%[1]d", "notes": [
+						{"id": %[1]d3, "body": "approved this merge request", "author": {"username": "bob"},
+						 "created_at": "2023-01-03T00:00:00Z", "system": true}
+					]}
+				]`, page)
+			}))
+			defer srv.Close()
+
+			g, err := NewGitlab("token", srv.URL, "test", Limits{})
+			require.NoError(t, err)
+
+			threads, evs, err := g.loadDiscussions(context.Background(), 1, 2)
+			require.NoError(t, err)
+
+			require.Len(t, threads, pages)
+			for idx, thread := range threads {
+				assert.Equal(t, fmt.Sprintf("d%d", idx+1), thread.DiscussionID)
+				assert.Equal(t, "alice", thread.Author.Username)
+				require.NotNil(t, thread.Child)
+				assert.Equal(t, "bob", thread.Child.Author.Username)
+			}
+
+			// a comment, a reply and an approval on each page
+			assert.Len(t, evs, 3*pages)
+
+			assert.LessOrEqual(t, maxInFlight.Load(), int32(gitlabConcurrency))
+			if tt.totalPages {
+				assert.Greater(t, maxInFlight.Load(), int32(1), "pages must be listed concurrently")
+			} else {
+				assert.Equal(t, int32(1), maxInFlight.Load(), "pages must be listed one by one")
+			}
+		})
+	}
+}
+
+func TestGitlab_loadDiffStats(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/graphql", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		var req struct {
+			Variables map[string]any `json:"variables"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, map[string]any{"path": "group/project", "iid": "2"}, req.Variables)
+
+		_, _ = w.Write([]byte(`{"data": {"project": {"mergeRequest": {
+			"diffStatsSummary": {"additions": 1500, "deletions": 300, "fileCount": 1200}
+		}}}}`))
+	}))
+	defer srv.Close()
+
+	g, err := NewGitlab("token", srv.URL, "test", Limits{})
+	require.NoError(t, err)
+
+	stats, err := g.loadDiffStats(context.Background(), "group/project", 2)
+	require.NoError(t, err)
+	assert.Equal(t, git.DiffStats{Files: 1200, Additions: 1500, Deletions: 300}, stats)
+}
+
+func TestGitlabGraphQLURL(t *testing.T) {
+	tests := []struct {
+		restURL string
+		want    string
+	}{
+		{restURL: "https://gitlab.com/api/v4/", want: "https://gitlab.com/api/graphql"},
+		{restURL: "https://example.com/gitlab/api/v4", want: "https://example.com/gitlab/api/graphql"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.restURL, func(t *testing.T) {
+			u, err := url.Parse(tt.restURL)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, gitlabGraphQLURL(u))
+		})
+	}
+}
+
+func TestAttributePushes(t *testing.T) {
+	at := func(min, sec int) time.Time { return time.Date(2023, 1, 1, 12, min, sec, 0, time.UTC) }
+	alice, bob := git.User{Username: "alice"}, git.User{Username: "bob"}
+
+	tests := []struct {
+		name        string
+		history     []git.Event
+		pushes      []git.Event
+		wantHistory []git.Event
+		wantPushes  []git.Event
+	}{
+		{
+			name: "notes set actors of the closest versions and are removed",
+			history: []git.Event{
+				{ID: "1", Actor: alice, Timestamp: at(0, 2), Type: git.EventTypePushed},
+				{ID: "2", Actor: bob, Timestamp: at(0, 10), Type: git.EventTypeApproved},
+				{ID: "3", Actor: bob, Timestamp: at(5, 1), Type: git.EventTypePushed},
+			},
+			pushes: []git.Event{
+				{ID: "version-1", Timestamp: at(0, 0), Type: git.EventTypePushed, ObjectID: "a"},
+				{ID: "version-2", Timestamp: at(5, 0), Type: git.EventTypePushed, ObjectID: "b"},
+			},
+			wantHistory: []git.Event{{ID: "2", Actor: bob, Timestamp: at(0, 10), Type: git.EventTypeApproved}},
+			wantPushes: []git.Event{
+				{ID: "version-1", Actor: alice, Timestamp: at(0, 0), Type: git.EventTypePushed, ObjectID: "a"},
+				{ID: "version-2", Actor: bob, Timestamp: at(5, 0), Type: git.EventTypePushed, ObjectID: "b"},
+			},
+		},
+		{
+			name: "each version is attributed once",
+			history: []git.Event{
+				{ID: "1", Actor: alice, Timestamp: at(0, 1), Type: git.EventTypePushed},
+				{ID: "2", Actor: bob, Timestamp: at(0, 3), Type: git.EventTypePushed},
+			},
+			pushes: []git.Event{
+				{ID: "version-1", Timestamp: at(0, 0), Type: git.EventTypePushed},
+				{ID: "version-2", Timestamp: at(0, 4), Type: git.EventTypePushed},
+			},
+			wantHistory: []git.Event{},
+			wantPushes: []git.Event{
+				{ID: "version-1", Actor: alice, Timestamp: at(0, 0), Type: git.EventTypePushed},
+				{ID: "version-2", Actor: bob, Timestamp: at(0, 4), Type: git.EventTypePushed},
+			},
+		},
+		{
+			name:        "notes far from versions are removed without attribution",
+			history:     []git.Event{{ID: "1", Actor: alice, Timestamp: at(3, 0), Type: git.EventTypePushed}},
+			pushes:      []git.Event{{ID: "version-1", Timestamp: at(0, 0), Type: git.EventTypePushed}},
+			wantHistory: []git.Event{},
+			wantPushes:  []git.Event{{ID: "version-1", Timestamp: at(0, 0), Type: git.EventTypePushed}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantHistory, attributePushes(tt.history, tt.pushes))
+			assert.Equal(t, tt.wantPushes, tt.pushes)
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-pkgz/requester/middleware"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Limits bounds the load of a single engine on its API.
+type Limits struct {
+	Concurrency int           // max number of requests in flight, zero means no limit
+	Retries     int           // max number of retries of a throttled or failed request
+	Backoff     time.Duration // delay before the first retry, doubled on each next one
+}
+
+// RateLimitError is returned when the API holds requests for longer,
+// than the request is allowed to wait.
+type RateLimitError struct {
+	Until time.Time
+}
+
+// Error implements error interface.
+func (e RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited until %s", e.Until.Format(time.DateTime))
+}
+
+// limiter is an HTTP middleware, that shares the budget of requests among all
+// requests of the engine: it bounds the number of requests in flight, retries
+// throttled ones and holds all requests, once the API reports the exhausted limit.
+type limiter struct {
+	Limits
+	sem chan struct{}
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+}
+
+func newLimiter(limits Limits) *limiter {
+	l := &limiter{Limits: limits}
+	if limits.Concurrency > 0 {
+		l.sem = make(chan struct{}, limits.Concurrency)
+	}
+	return l
+}
+
+// Middleware wraps the transport with the limiter.
+func (l *limiter) Middleware(next http.RoundTripper) http.RoundTripper {
+	return middleware.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		ctx := req.Context()
+		span := trace.SpanFromContext(ctx)
+
+		for attempt := 0; ; attempt++ {
+			if attempt > 0 && req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, fmt.Errorf("rewind request body: %w", err)
+				}
+				req.Body = body
+			}
+
+			resp, err := l.roundTrip(next, req)
+
+			if resp != nil {
+				l.observe(span, req, resp)
+			}
+
+			if attempt >= l.Retries || !retryable(req, resp, err) {
+				return resp, err
+			}
+
+			delay := l.Backoff << attempt
+			if resp != nil {
+				if d, ok := retryDelay(resp); ok {
+					delay = d
+				}
+			}
+
+			// the wait must fit into the deadline of the request, i.e. the timeout
+			// of the client, otherwise the retry would fail anyway
+			if until := time.Now().Add(delay); !beforeDeadline(ctx, until) {
+				if resp == nil || !throttled(resp) {
+					return resp, err
+				}
+				l.pause(delay)
+				discard(resp)
+				return nil, RateLimitError{Until: until}
+			}
+
+			if resp != nil {
+				if throttled(resp) {
+					l.pause(delay)
+				}
+				discard(resp)
+			}
+
+			reason := fmt.Sprintf("%v", err)
+			if resp != nil {
+				reason = resp.Status
+			}
+
+			log.Printf("[DEBUG] retrying %s %s in %s, attempt %d/%d: %s",
+				req.Method, req.URL, delay, attempt+1, l.Retries, reason)
+			span.AddEvent("retry", trace.WithAttributes(
+				attribute.String("url", req.URL.String()),
+				attribute.String("reason", reason),
+				attribute.String("delay", delay.String()),
+				attribute.Int("attempt", attempt+1),
+			))
+
+			if err := sleep(ctx, delay); err != nil {
+				return nil, err
+			}
+		}
+	})
+}
+
+// roundTrip makes the request within the budget.
+func (l *limiter) roundTrip(next http.RoundTripper, req *http.Request) (*http.Response, error) {
+	l.mu.Lock()
+	until := l.pausedUntil
+	l.mu.Unlock()
+
+	if !beforeDeadline(req.Context(), until) {
+		return nil, RateLimitError{Until: until}
+	}
+
+	if err := sleep(req.Context(), time.Until(until)); err != nil {
+		return nil, err
+	}
+
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+			defer func() { <-l.sem }()
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	return next.RoundTrip(req)
+}
+
+// observe reports the state of the rate limit from the response headers and
+// holds further requests, if the limit is exhausted.
+// Gitlab reports them as RateLimit-*, while Github as X-RateLimit-*.
+func (l *limiter) observe(span trace.Span, req *http.Request, resp *http.Response) {
+	header := func(name string) string {
+		if v := resp.Header.Get(name); v != "" {
+			return v
+		}
+		return resp.Header.Get("X-" + name)
+	}
+
+	limit, remaining, reset := header("RateLimit-Limit"), header("RateLimit-Remaining"), header("RateLimit-Reset")
+	if remaining == "" {
+		return
+	}
+
+	log.Printf("[DEBUG] rate limit at %s: %s/%s remaining, resets at %s", req.URL.Host, remaining, limit, reset)
+	span.SetAttributes(
+		attribute.String("ratelimit.limit", limit),
+		attribute.String("ratelimit.remaining", remaining),
+		attribute.String("ratelimit.reset", reset),
+	)
+
+	if remaining != "0" {
+		return
+	}
+
+	if ts, err := strconv.ParseInt(reset, 10, 64); err == nil {
+		log.Printf("[WARN] rate limit at %s is exhausted, holding requests until %s",
+			req.URL.Host, time.Unix(ts, 0).Format(time.TimeOnly))
+		l.pause(time.Until(time.Unix(ts, 0)))
+	}
+}
+
+// pause holds all requests for the given duration.
+func (l *limiter) pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// retryable returns true if the request was throttled, or, for idempotent
+// requests, failed because of the network, or the server is temporarily
+// unavailable. Other requests might have been processed by the server,
+// so they're retried only when throttled.
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if resp != nil && throttled(resp) {
+		return true
+	}
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) &&
+			!errors.As(err, &RateLimitError{})
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// throttled returns true if the request was rejected because of the rate limit.
+// Github responds with 403 on the exhausted primary rate limit.
+func throttled(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusForbidden && resp.Header.Get("X-RateLimit-Remaining") == "0")
+}
+
+// retryDelay returns the delay, requested by the server in the Retry-After
+// header, in seconds or as a date, or the time until the rate limit resets,
+// if the request was throttled.
+func retryDelay(resp *http.Response) (time.Duration, bool) {
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return time.Until(t), true
+		}
+	}
+
+	if !throttled(resp) {
+		return 0, false
+	}
+
+	for _, h := range []string{"RateLimit-Reset", "X-RateLimit-Reset"} {
+		if ts, err := strconv.ParseInt(resp.Header.Get(h), 10, 64); err == nil {
+			return time.Until(time.Unix(ts, 0)), true
+		}
+	}
+
+	return 0, false
+}
+
+// beforeDeadline returns true if the given time comes before the deadline
+// of the context, if it has one.
+func beforeDeadline(ctx context.Context, t time.Time) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || t.Before(deadline)
+}
+
+// discard drains and closes the body of the response, that is not returned
+// to the caller, to reuse the connection.
+func discard(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+}
+
+// sleep waits for the given duration or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,182 @@
+package engine
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newLimitedClient returns a client to the server, whose handler responds
+// with the given statuses and headers in turn, and then with 200.
+func newLimitedClient(t *testing.T, limits Limits, timeout time.Duration, resps ...func(w http.ResponseWriter)) (*http.Client, string, *int32) {
+	t.Helper()
+
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1))
+		if n <= len(resps) {
+			resps[n-1](w)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(ts.Close)
+
+	cl := &http.Client{Transport: newLimiter(limits).Middleware(http.DefaultTransport), Timeout: timeout}
+	return cl, ts.URL, &calls
+}
+
+func respond(status int, headers ...string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		for idx := 0; idx+1 < len(headers); idx += 2 {
+			w.Header().Set(headers[idx], headers[idx+1])
+		}
+		w.WriteHeader(status)
+	}
+}
+
+func TestLimiter_retries(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		resps     []func(w http.ResponseWriter)
+		wantCode  int
+		wantCalls int32
+		minDelay  time.Duration
+	}{
+		{
+			name:      "429 waits for Retry-After",
+			method:    http.MethodGet,
+			resps:     []func(w http.ResponseWriter){respond(http.StatusTooManyRequests, "Retry-After", "1")},
+			wantCode:  http.StatusOK,
+			wantCalls: 2,
+			minDelay:  time.Second,
+		},
+		{
+			name:   "403 with exhausted limit waits for its reset",
+			method: http.MethodGet,
+			resps: []func(w http.ResponseWriter){func(w http.ResponseWriter) {
+				reset := strconv.FormatInt(time.Now().Add(2*time.Second).Unix(), 10)
+				respond(http.StatusForbidden, "X-RateLimit-Remaining", "0", "X-RateLimit-Reset", reset)(w)
+			}},
+			wantCode:  http.StatusOK,
+			wantCalls: 2,
+			minDelay:  time.Second,
+		},
+		{
+			name:      "403 without exhausted limit is not retried",
+			method:    http.MethodGet,
+			resps:     []func(w http.ResponseWriter){respond(http.StatusForbidden)},
+			wantCode:  http.StatusForbidden,
+			wantCalls: 1,
+		},
+		{
+			name:   "502 is retried until retries run out",
+			method: http.MethodGet,
+			resps: []func(w http.ResponseWriter){
+				respond(http.StatusBadGateway),
+				respond(http.StatusBadGateway),
+				respond(http.StatusBadGateway),
+				respond(http.StatusBadGateway),
+			},
+			wantCode:  http.StatusBadGateway,
+			wantCalls: 3,
+		},
+		{
+			name:      "502 of POST is not retried",
+			method:    http.MethodPost,
+			resps:     []func(w http.ResponseWriter){respond(http.StatusBadGateway)},
+			wantCode:  http.StatusBadGateway,
+			wantCalls: 1,
+		},
+		{
+			name:      "throttled POST is retried",
+			method:    http.MethodPost,
+			resps:     []func(w http.ResponseWriter){respond(http.StatusTooManyRequests, "Retry-After", "0")},
+			wantCode:  http.StatusOK,
+			wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limits := Limits{Retries: 2, Backoff: time.Millisecond}
+			cl, u, calls := newLimitedClient(t, limits, time.Minute, tt.resps...)
+
+			req, err := http.NewRequest(tt.method, u, http.NoBody)
+			require.NoError(t, err)
+
+			start := time.Now()
+			resp, err := cl.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.wantCode, resp.StatusCode)
+			assert.Equal(t, tt.wantCalls, atomic.LoadInt32(calls))
+			assert.GreaterOrEqual(t, time.Since(start), tt.minDelay)
+		})
+	}
+}
+
+func TestLimiter_waitBeyondTimeout(t *testing.T) {
+	limits := Limits{Retries: 2, Backoff: time.Millisecond}
+	cl, u, calls := newLimitedClient(t, limits, time.Second,
+		respond(http.StatusTooManyRequests, "Retry-After", "3600"))
+
+	start := time.Now()
+	_, err := cl.Get(u)
+	require.Error(t, err)
+
+	var rlErr RateLimitError
+	require.True(t, errors.As(err, &rlErr), "unexpected error: %v", err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), rlErr.Until, time.Minute)
+	assert.Less(t, time.Since(start), time.Second, "must not wait for the timeout")
+
+	// next requests are held as well, without hitting the server
+	_, err = cl.Get(u)
+	assert.True(t, errors.As(err, &rlErr), "unexpected error: %v", err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
+
+func TestLimiter_concurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+
+		for {
+			prev := atomic.LoadInt32(&maxInFlight)
+			if n <= prev || atomic.CompareAndSwapInt32(&maxInFlight, prev, n) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	cl := &http.Client{Transport: newLimiter(Limits{Concurrency: 2}).Middleware(http.DefaultTransport)}
+
+	var wg sync.WaitGroup
+	for idx := 0; idx < 10; idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := cl.Get(ts.URL)
+			if assert.NoError(t, err) {
+				_ = resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxInFlight))
+}
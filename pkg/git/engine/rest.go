@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// restClient is a tiny JSON API client for engines, that don't have
+// a dedicated client library.
+type restClient struct {
+	cl      *http.Client
+	baseURL string
+	headers map[string]string
+}
+
+// StatusError is returned when the API responds with an unexpected status code.
+type StatusError struct {
+	Code int
+	Body string
+}
+
+// Error implements error interface.
+func (e StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d: %s", e.Code, e.Body)
+}
+
+// get makes a GET request to the given path and decodes the response into out.
+func (c *restClient) get(ctx context.Context, path string, query url.Values, out any) (http.Header, error) {
+	return c.do(ctx, http.MethodGet, path, query, nil, out)
+}
+
+// post makes a POST request to the given path with the JSON-encoded body
+// and decodes the response into out.
+func (c *restClient) post(ctx context.Context, path string, body, out any) (http.Header, error) {
+	return c.do(ctx, http.MethodPost, path, nil, body, out)
+}
+
+// graphql executes the query against the GraphQL endpoint at the base URL
+// and decodes the data of the response into out.
+func (c *restClient) graphql(ctx context.Context, query string, vars map[string]any, out any) error {
+	var resp struct {
+		Data   any `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	resp.Data = out
+
+	body := map[string]any{"query": query, "variables": vars}
+	if _, err := c.post(ctx, "", body, &resp); err != nil {
+		return fmt.Errorf("call graphql api: %w", err)
+	}
+
+	if len(resp.Errors) > 0 {
+		msgs := make([]string, len(resp.Errors))
+		for idx, e := range resp.Errors {
+			msgs[idx] = e.Message
+		}
+		return fmt.Errorf("graphql api returned errors: %v", msgs)
+	}
+
+	return nil
+}
+
+func (c *restClient) do(ctx context.Context, method, path string, query url.Values, body, out any) (http.Header, error) {
+	u := strings.TrimSuffix(c.baseURL, "/")
+	// empty path addresses the base URL itself, e.g. the GraphQL endpoint
+	if path != "" {
+		u += "/" + strings.TrimPrefix(path, "/")
+	}
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("marshal request body: %w", err)
+		}
+		rd = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, rd)
+	if err != nil {
+		return nil, fmt.Errorf("make request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.cl.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return resp.Header, StatusError{Code: resp.StatusCode, Body: string(b)}
+	}
+
+	if out == nil {
+		return resp.Header, nil
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp.Header, fmt.Errorf("decode response: %w", err)
+	}
+
+	return resp.Header, nil
+}
@@ -18,6 +18,8 @@ const (
 
 // PullRequest describes a pull request.
 type PullRequest struct {
+	// Instance is the name of the git engine instance, the pull request was loaded from.
+	Instance     string   `json:"instance"`
 	URL          string   `json:"url"`
 	Number       int      `json:"number"`
 	Project      Project  `json:"project"`
@@ -33,13 +35,174 @@ type PullRequest struct {
 		By             []User `json:"by"`
 		SatisfiesRules bool   `json:"satisfies_rules"`
 		Required       int    `json:"required"`
-	}
-	History []Event   `json:"history"`
-	Threads []Comment `json:"threads"`
-	State   State     `json:"state"`
+	} `json:"approvals"`
+	History  []Event   `json:"history"`
+	Threads  []Comment `json:"threads"`
+	State    State     `json:"state"`
+	Pipeline Pipeline  `json:"pipeline"`
+
+	Mergeability Mergeability `json:"mergeability"`
+	Diff         DiffStats    `json:"diff"`
 
 	ClosedAt  time.Time `json:"closed_at"`
 	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// LastActivityAt is the time of the latest event, made by a user, or
+	// the time of creation, if there are no such events.
+	LastActivityAt time.Time `json:"last_activity_at"`
+}
+
+// LatestActivity returns the time of the latest event in the history,
+// made by a user, not by the system, or the creation time, if there
+// are no such events.
+func (pr PullRequest) LatestActivity() time.Time {
+	ev, ok := pr.LastEvent(func(ev Event) bool { return ev.Actor != SystemUser })
+	if !ok || ev.Timestamp.Before(pr.CreatedAt) {
+		return pr.CreatedAt
+	}
+	return ev.Timestamp
+}
+
+// DiffStats describes the size of the changes.
+type DiffStats struct {
+	Files     int `json:"files"`
+	Additions int `json:"additions"`
+	Deletions int `json:"deletions"`
+}
+
+// Lines returns the total number of changed lines.
+func (d DiffStats) Lines() int { return d.Additions + d.Deletions }
+
+// Mergeability describes whether the pull request is ready to be merged.
+type Mergeability struct {
+	HasConflicts         bool `json:"has_conflicts"`
+	NeedsRebase          bool `json:"needs_rebase"` // source branch is behind the target one and must be rebased
+	BlockedByDiscussions bool `json:"blocked_by_discussions"`
+	// Status is an engine-specific detailed merge status, e.g. "mergeable" or "ci_must_pass".
+	Status string `json:"status"`
+}
+
+// RebaseRequired returns true if the author has to rebase the source branch,
+// either to resolve conflicts, or to catch up with the target branch.
+func (m Mergeability) RebaseRequired() bool { return m.HasConflicts || m.NeedsRebase }
+
+// PipelineStatus is a status of the CI pipeline.
+type PipelineStatus string
+
+const (
+	// PipelineStatusNone means there is no pipeline for the pull request.
+	PipelineStatusNone PipelineStatus = ""
+	// PipelineStatusPending is a status of a pipeline, that waits to be run.
+	PipelineStatusPending PipelineStatus = "pending"
+	// PipelineStatusRunning is a status of a running pipeline.
+	PipelineStatusRunning PipelineStatus = "running"
+	// PipelineStatusSuccess is a status of a successfully finished pipeline.
+	PipelineStatusSuccess PipelineStatus = "success"
+	// PipelineStatusFailed is a status of a failed pipeline.
+	PipelineStatusFailed PipelineStatus = "failed"
+	// PipelineStatusCanceled is a status of a canceled pipeline.
+	PipelineStatusCanceled PipelineStatus = "canceled"
+	// PipelineStatusSkipped is a status of a skipped pipeline.
+	PipelineStatusSkipped PipelineStatus = "skipped"
+	// PipelineStatusManual is a status of a pipeline, that waits for a manual action.
+	PipelineStatusManual PipelineStatus = "manual"
+)
+
+// Settled returns true if the status won't change by itself, without
+// new commits, i.e. the pipeline is finished, or there is none.
+func (s PipelineStatus) Settled() bool {
+	switch s {
+	case PipelineStatusPending, PipelineStatusRunning, PipelineStatusManual:
+		return false
+	default:
+		return true
+	}
+}
+
+// Pipeline describes the CI pipeline of the latest commit of the pull request.
+type Pipeline struct {
+	Status     PipelineStatus `json:"status"`
+	URL        string         `json:"url"`
+	FailedJobs []string       `json:"failed_jobs"` // names of failed jobs, that are not allowed to fail
+	FinishedAt time.Time      `json:"finished_at"`
+}
+
+// LastEvent returns the last event in the history, that satisfies the predicate.
+// History must be sorted in ascending order.
+func (pr PullRequest) LastEvent(fn func(Event) bool) (Event, bool) {
+	for idx := len(pr.History) - 1; idx >= 0; idx-- {
+		if fn(pr.History[idx]) {
+			return pr.History[idx], true
+		}
+	}
+	return Event{}, false
+}
+
+// ApprovedSince returns the users, who approved the pull request after the
+// given time and didn't revoke their approvals since then.
+func (pr PullRequest) ApprovedSince(t time.Time) []User {
+	var users []User
+	seen := map[string]bool{}
+	for idx := len(pr.History) - 1; idx >= 0; idx-- {
+		ev := pr.History[idx]
+		if !ev.Timestamp.After(t) {
+			break
+		}
+
+		if ev.Type != EventTypeApproved && ev.Type != EventTypeUnapproved {
+			continue
+		}
+
+		// only the latest approval-related event of each user matters
+		if seen[ev.Actor.Username] {
+			continue
+		}
+		seen[ev.Actor.Username] = true
+
+		if ev.Type == EventTypeApproved {
+			users = append(users, ev.Actor)
+		}
+	}
+	return users
+}
+
+// NeedsReReview returns true if the user has approved the pull request,
+// but new commits were pushed after the approval.
+func (pr PullRequest) NeedsReReview(u User) bool {
+	approval, ok := pr.LastEvent(func(ev Event) bool {
+		return ev.Actor.Username == u.Username &&
+			(ev.Type == EventTypeApproved || ev.Type == EventTypeUnapproved)
+	})
+	if !ok || approval.Type != EventTypeApproved {
+		return false
+	}
+
+	return pr.ChangedSince(approval.Timestamp)
+}
+
+// LastReviewBy returns the last approval, comment, or request for changes,
+// made by the user.
+func (pr PullRequest) LastReviewBy(u User) (Event, bool) {
+	return pr.LastEvent(func(ev Event) bool {
+		if ev.Actor.Username != u.Username {
+			return false
+		}
+		switch ev.Type {
+		case EventTypeApproved, EventTypeCommented, EventTypeReplied, EventTypeChangesRequested:
+			return true
+		default:
+			return false
+		}
+	})
+}
+
+// ChangedSince returns true if new commits were pushed to the pull
+// request after the given time.
+func (pr PullRequest) ChangedSince(t time.Time) bool {
+	change, ok := pr.LastEvent(func(ev Event) bool {
+		return ev.Type == EventTypePushed
+	})
+	return ok && change.Timestamp.After(t)
 }
 
 // Project holds project data.
@@ -60,7 +223,14 @@ var SystemUser = User{Username: "system"}
 
 // Comment describes a comment.
 type Comment struct {
+	// DiscussionID is a stable ID of the thread, the comment belongs to.
+	DiscussionID string `json:"discussion_id"`
+	// NoteID is an ID of the comment itself.
+	NoteID    string    `json:"note_id"`
 	Author    User      `json:"author"`
+	Body      string    `json:"body"`
+	File      string    `json:"file"` // empty for general discussions
+	Line      int       `json:"line"` // zero for general discussions
 	CreatedAt time.Time `json:"created_at"`
 	Resolved  bool      `json:"resolved"`
 	Child     *Comment  `json:"child"`
@@ -81,6 +251,10 @@ type Event struct {
 	Actor     User      `json:"actor"`
 	Timestamp time.Time `json:"timestamp"`
 	Type      EventType `json:"type"`
+	// Approximate is true if the engine doesn't report the time of the event,
+	// and the timestamp is the latest known time before it, e.g. the time of
+	// the last comment of a resolved thread.
+	Approximate bool `json:"approximate"`
 
 	ObjectID   string     `json:"object_id"`
 	ObjectType ObjectType `json:"object_type"`
@@ -93,22 +267,29 @@ type EventType string
 // If not explicitly specified, object id and type will be empty.
 const (
 	// EventTypeThreadResolved is a pull request event type for a resolution of a thread.
-	// Object ID will be a position (file:line) thread (root comment) ID and type
-	// will be "comment".
+	// Object ID will be a discussion ID of the thread and type will be "comment".
 	EventTypeThreadResolved EventType = "resolved"
-	// EventTypeCommented is a pull request event type for a comment.
-	// If a comment is a reply to another comment, object ID will be a position of
-	// parent comment (file:line) and type will be "comment".
+	// EventTypeCommented is a pull request event type for a comment, that starts
+	// a thread. Object ID will be a discussion ID of the thread and type will
+	// be "comment".
 	EventTypeCommented EventType = "commented"
 	// EventTypeReplied is a pull request event type for a reply to a comment.
-	// Object ID will be a position of parent comment (file:line) and type will be
-	// "comment".
+	// Object ID will be a discussion ID of the thread and type will be "comment".
 	EventTypeReplied EventType = "replied"
 
 	// EventTypeApproved is a pull request event type for an approval.
 	EventTypeApproved EventType = "approved"
 	// EventTypeUnapproved is a pull request event type for an unapproval.
 	EventTypeUnapproved EventType = "unapproved"
+	// EventTypeChangesRequested is a pull request event type for a review,
+	// that requests changes.
+	EventTypeChangesRequested EventType = "changes_requested"
+
+	// EventTypePushed is a pull request event type for a push of new commits
+	// to the source branch. If the engine reports it, object ID will be the SHA
+	// of the head commit and type will be "commit". Actor may be empty, if the
+	// engine can't tell the user, who pushed the commits.
+	EventTypePushed EventType = "pushed"
 )
 
 // ObjectType defines an object over which an event was performed.
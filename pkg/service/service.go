@@ -5,6 +5,7 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/Semior001/glmrl/pkg/git"
 	"github.com/Semior001/glmrl/pkg/git/engine"
@@ -12,23 +13,76 @@ import (
 	"github.com/samber/lo"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/errgroup"
 	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// Service wraps git engine client with additional functionality.
+// detailsConcurrency limits the number of pull requests, which details are loaded at once.
+const detailsConcurrency = 8
+
+// Service wraps git engine clients with additional functionality.
+// It might serve several instances of git engines at once, e.g. gitlab.com and
+// a self-hosted gitlab, in this case, the results of all instances are merged.
 type Service struct {
+	instances map[string]instance
+}
+
+type instance struct {
 	eng engine.Interface
 	me  git.User
 }
 
-// NewService creates a new service.
-func NewService(ctx context.Context, engine engine.Interface) (*Service, error) {
-	me, err := engine.GetCurrentUser(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("get current user: %w", err)
+// NewService creates a new service over the given engines, keyed by the name of
+// the instance.
+func NewService(ctx context.Context, engines map[string]engine.Interface) (*Service, error) {
+	if len(engines) == 0 {
+		return nil, errors.New("no engines provided")
 	}
 
-	return &Service{eng: engine, me: me}, nil
+	s := &Service{instances: make(map[string]instance, len(engines))}
+
+	mu := &sync.Mutex{}
+	ewg, ctx := errgroup.WithContext(ctx)
+	for name, eng := range engines {
+		name, eng := name, eng
+		ewg.Go(func() error {
+			me, err := eng.GetCurrentUser(ctx)
+			if err != nil {
+				return fmt.Errorf("get current user at %s: %w", name, err)
+			}
+
+			mu.Lock()
+			s.instances[name] = instance{eng: eng, me: me}
+			mu.Unlock()
+			return nil
+		})
+	}
+
+	if err := ewg.Wait(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Instances returns the names of the instances served by the service.
+func (s *Service) Instances() []string {
+	names := lo.Keys(s.instances)
+	sort.Strings(names)
+	return names
+}
+
+// CurrentUser returns the user, authorized at the given instance.
+func (s *Service) CurrentUser(instance string) git.User { return s.instances[instance].me }
+
+// isMe returns true if the user is the current user at the pull request's instance.
+func (s *Service) isMe(pr git.PullRequest, u git.User) bool {
+	return s.instances[pr.Instance].me.Username == u.Username
 }
 
 // ListPRsRequest is a request to list pull requests.
@@ -36,33 +90,98 @@ type ListPRsRequest struct {
 	engine.ListPRsRequest
 
 	WithoutMyUnresolvedThreads bool
+	ApprovedSinceMyLastPush    bool
+	NewCommitsSinceMyReview    bool
+	Pipeline                   git.PipelineStatus
+	RebaseRequired             *bool
+	MaxLines                   int           // zero means no limit
+	MaxFiles                   int           // zero means no limit
+	UpdatedWithin              time.Duration // zero means no limit
+	CreatedWithin              time.Duration // zero means no limit
+	StaleFor                   time.Duration // zero means no limit
+	Reviewer                   string
+	Assignee                   string
+	Group                      string
+	Search                     string
+	TargetBranch               string
 	ApprovedByMe               *bool
+	Details                    engine.Details // details to load, besides the ones, needed by filters
 	SatisfiesApprovalRules     *bool
 	Authors                    misc.Filter[string]
 	ProjectPaths               misc.Filter[string]
+	Where                      *Where
+
+	// Progress, if set, is called with the number of pull requests, which
+	// details are loaded, and the total number of them, it might be called concurrently.
+	Progress func(done, total int) `json:"-"`
 }
 
 // ListPullRequests calls an underlying git engine client to list pull requests and filters them by the provided
-// criteria.
+// criteria. Details of pull requests are loaded only for the ones, that passed the filters, which don't need them.
 func (s *Service) ListPullRequests(ctx context.Context, req ListPRsRequest) ([]git.PullRequest, error) {
 	log.Printf("[DEBUG] list pull requests with criteria %+v", req)
 
-	prs, err := s.listPRs(ctx, req)
+	prs, err := s.listPRs(ctx, pushDown(req))
 	if err != nil {
 		return nil, fmt.Errorf("list pull requests: %w", err)
 	}
 
 	log.Printf("[DEBUG] listed %d pull requests", len(prs))
 
-	filter := func(name string, fn func(git.PullRequest) bool) {
+	prs, _, err = s.filterPRs(ctx, req, prs)
+	return prs, err
+}
+
+// ListChangedPullRequests lists pull requests, updated after the given time, and splits them into the ones,
+// that satisfy the criteria, and the ones, that don't satisfy them anymore.
+func (s *Service) ListChangedPullRequests(ctx context.Context, req ListPRsRequest, since time.Time) (matched, rejected []git.PullRequest, err error) {
+	log.Printf("[DEBUG] list pull requests, changed since %s, with criteria %+v", since, req)
+
+	ereq := pushDown(req)
+	if since.After(ereq.UpdatedAfter) {
+		ereq.UpdatedAfter = since
+	}
+
+	// pull requests, that left the requested state, e.g. merged ones, must be
+	// listed as well, so that they're rejected by the state filter
+	ereq.State = ""
+
+	prs, err := s.listPRs(ctx, ereq)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list pull requests: %w", err)
+	}
+
+	// engines might ignore the time, unchanged pull requests must not be rejected
+	prs = lo.Filter(prs, func(pr git.PullRequest, _ int) bool { return pr.UpdatedAt.After(since) })
+
+	log.Printf("[DEBUG] listed %d changed pull requests", len(prs))
+
+	return s.filterPRs(ctx, req, prs)
+}
+
+// filterPRs splits pull requests into the ones, that satisfy the criteria, and the ones, that don't.
+func (s *Service) filterPRs(ctx context.Context, req ListPRsRequest, prs []git.PullRequest) (matched, rejected []git.PullRequest, err error) {
+	type prFilter struct {
+		name  string
+		needs engine.Details
+		fn    func(git.PullRequest) bool
+	}
+
+	var filters []prFilter
+	filter := func(name string, needs engine.Details, fn func(git.PullRequest) bool) {
+		filters = append(filters, prFilter{name: name, needs: needs, fn: fn})
+	}
+
+	apply := func(f prFilter) {
 		_, span := otel.GetTracerProvider().Tracer("service").
-			Start(ctx, fmt.Sprintf("filter PRs by %s", name))
+			Start(ctx, fmt.Sprintf("filter PRs by %s", f.name))
 		defer span.End()
 
 		var filteredURLs []string
 		prs = lo.Filter(prs, func(pr git.PullRequest, _ int) bool {
-			if !fn(pr) {
+			if !f.fn(pr) {
 				filteredURLs = append(filteredURLs, pr.URL)
+				rejected = append(rejected, pr)
 				return false
 			}
 			return true
@@ -77,83 +196,279 @@ func (s *Service) ListPullRequests(ctx context.Context, req ListPRsRequest) ([]g
 		span.SetAttributes(attribute.StringSlice("filtered_urls", filteredURLs))
 	}
 
+	// engines might return pull requests in other states, e.g. if the API doesn't
+	// distinguish drafts, drafts are listed only if they're requested explicitly
+	filter("state", engine.DetailsNone, func(pr git.PullRequest) bool {
+		if req.State == "" {
+			return pr.State != git.StateDraft
+		}
+		return pr.State == req.State
+	})
+
+	if len(req.Labels.Include) > 0 {
+		filter("labels include", engine.DetailsNone, func(pr git.PullRequest) bool {
+			return lo.Every(pr.Labels, req.Labels.Include)
+		})
+	}
+
+	if len(req.Labels.Exclude) > 0 {
+		filter("labels exclude", engine.DetailsNone, func(pr git.PullRequest) bool {
+			return !lo.Some(pr.Labels, req.Labels.Exclude)
+		})
+	}
+
 	if req.ApprovedByMe != nil {
-		filter("approved by me", func(pr git.PullRequest) bool {
+		filter("approved by me", engine.DetailsApprovals, func(pr git.PullRequest) bool {
 			return lo.ContainsBy(pr.Approvals.By, func(u git.User) bool {
-				return u.Username == s.me.Username
+				return s.isMe(pr, u)
 			}) == *req.ApprovedByMe
 		})
 	}
 
 	if req.WithoutMyUnresolvedThreads {
-		filter("without my unresolved threads", func(pr git.PullRequest) bool {
+		filter("without my unresolved threads", engine.DetailsDiscussions, func(pr git.PullRequest) bool {
 			return !lo.ContainsBy(pr.Threads, func(thread git.Comment) bool {
-				myUnresolvedThread := thread.Author.Username == s.me.Username && !thread.Resolved
-				lastCommentMine := thread.Last().Author.Username == s.me.Username
+				myUnresolvedThread := s.isMe(pr, thread.Author) && !thread.Resolved
+				lastCommentMine := s.isMe(pr, thread.Last().Author)
 				return myUnresolvedThread && lastCommentMine
 			})
 		})
 	}
 
+	if req.ApprovedSinceMyLastPush {
+		filter("approved since my last push", engine.DetailsDiscussions|engine.DetailsCommits, func(pr git.PullRequest) bool {
+			since := pr.CreatedAt
+			if push, ok := pr.LastEvent(func(ev git.Event) bool {
+				return ev.Type == git.EventTypePushed && s.isMe(pr, ev.Actor)
+			}); ok {
+				since = push.Timestamp
+			}
+			return len(pr.ApprovedSince(since)) > 0
+		})
+	}
+
+	if req.NewCommitsSinceMyReview {
+		filter("new commits since my review", engine.DetailsDiscussions|engine.DetailsCommits, func(pr git.PullRequest) bool {
+			review, ok := pr.LastReviewBy(s.instances[pr.Instance].me)
+			return ok && pr.ChangedSince(review.Timestamp)
+		})
+	}
+
 	if req.SatisfiesApprovalRules != nil {
-		filter("satisfies approval rules", func(pr git.PullRequest) bool {
+		filter("satisfies approval rules", engine.DetailsApprovals, func(pr git.PullRequest) bool {
 			// we should not filter PR that satisfies approval rules, but the current user
 			// was explicitly requested to review this MR, and yet he didn't approve it
 			approvalRequiredFromMe := lo.ContainsBy(pr.Approvals.RequestedFrom, func(u git.User) bool {
-				return u.Username == s.me.Username
+				return s.isMe(pr, u)
 			})
 			approvedByMe := lo.ContainsBy(pr.Approvals.By, func(u git.User) bool {
-				return u.Username == s.me.Username
+				return s.isMe(pr, u)
 			})
 			return (approvalRequiredFromMe && !approvedByMe) ||
 				pr.Approvals.SatisfiesRules == *req.SatisfiesApprovalRules
 		})
 	}
 
+	if req.Pipeline != git.PipelineStatusNone {
+		filter("pipeline", engine.DetailsPipeline, func(pr git.PullRequest) bool { return pr.Pipeline.Status == req.Pipeline })
+	}
+
+	if req.RebaseRequired != nil {
+		filter("rebase required", engine.DetailsNone, func(pr git.PullRequest) bool {
+			return pr.Mergeability.RebaseRequired() == *req.RebaseRequired
+		})
+	}
+
+	if req.MaxLines > 0 {
+		filter("max lines", engine.DetailsDiff, func(pr git.PullRequest) bool { return pr.Diff.Lines() <= req.MaxLines })
+	}
+
+	if req.MaxFiles > 0 {
+		filter("max files", engine.DetailsDiff, func(pr git.PullRequest) bool { return pr.Diff.Files <= req.MaxFiles })
+	}
+
+	if req.UpdatedWithin > 0 {
+		filter("updated within", engine.DetailsNone, func(pr git.PullRequest) bool { return time.Since(pr.UpdatedAt) <= req.UpdatedWithin })
+	}
+
+	if req.CreatedWithin > 0 {
+		filter("created within", engine.DetailsNone, func(pr git.PullRequest) bool { return time.Since(pr.CreatedAt) <= req.CreatedWithin })
+	}
+
+	if req.StaleFor > 0 {
+		filter("stale for", engine.DetailsDiscussions|engine.DetailsCommits, func(pr git.PullRequest) bool { return time.Since(pr.LastActivityAt) >= req.StaleFor })
+	}
+
+	if req.Reviewer != "" {
+		filter("reviewer", engine.DetailsNone, func(pr git.PullRequest) bool {
+			return lo.ContainsBy(pr.Approvals.RequestedFrom, func(u git.User) bool { return u.Username == req.Reviewer })
+		})
+	}
+
+	if req.Assignee != "" {
+		filter("assignee", engine.DetailsNone, func(pr git.PullRequest) bool {
+			return lo.ContainsBy(pr.Assignees, func(u git.User) bool { return u.Username == req.Assignee })
+		})
+	}
+
+	if req.Search != "" {
+		filter("search", engine.DetailsNone, func(pr git.PullRequest) bool {
+			text := strings.ToLower(pr.Title + "\n" + pr.Body)
+			return strings.Contains(text, strings.ToLower(req.Search))
+		})
+	}
+
+	if req.TargetBranch != "" {
+		filter("target branch", engine.DetailsNone, func(pr git.PullRequest) bool { return pr.TargetBranch == req.TargetBranch })
+	}
+
+	if req.Group != "" {
+		filter("group", engine.DetailsNone, func(pr git.PullRequest) bool {
+			return strings.HasPrefix(pr.Project.FullPath, strings.TrimSuffix(req.Group, "/")+"/")
+		})
+	}
+
 	if len(req.Authors.Include) > 0 {
-		filter("authors include", func(pr git.PullRequest) bool {
+		filter("authors include", engine.DetailsNone, func(pr git.PullRequest) bool {
 			return lo.Contains(req.Authors.Include, pr.Author.Username)
 		})
 	}
 
 	if len(req.Authors.Exclude) > 0 {
-		filter("authors exclude", func(pr git.PullRequest) bool {
+		filter("authors exclude", engine.DetailsNone, func(pr git.PullRequest) bool {
 			return !lo.Contains(req.Authors.Exclude, pr.Author.Username)
 		})
 	}
 
 	if len(req.ProjectPaths.Include) > 0 {
-		filter("project paths include", func(pr git.PullRequest) bool {
+		filter("project paths include", engine.DetailsNone, func(pr git.PullRequest) bool {
 			return lo.Contains(req.ProjectPaths.Include, pr.Project.FullPath)
 		})
 	}
 
 	if len(req.ProjectPaths.Exclude) > 0 {
-		filter("project paths exclude", func(pr git.PullRequest) bool {
+		filter("project paths exclude", engine.DetailsNone, func(pr git.PullRequest) bool {
 			return !lo.Contains(req.ProjectPaths.Exclude, pr.Project.FullPath)
 		})
 	}
 
+	if req.Where != nil {
+		filter(fmt.Sprintf("where %s", req.Where), req.Where.Details(), func(pr git.PullRequest) bool {
+			return req.Where.Match(pr, s.instances[pr.Instance].me)
+		})
+	}
+
+	// cheap filters go first, so that the details are loaded
+	// only for pull requests, that passed them
+	details := req.Details
+	for _, f := range filters {
+		if f.needs == engine.DetailsNone {
+			apply(f)
+			continue
+		}
+		details |= f.needs
+	}
+
+	if prs, err = s.loadDetails(ctx, prs, details, req.Progress); err != nil {
+		return nil, nil, fmt.Errorf("load details: %w", err)
+	}
+
+	for _, f := range filters {
+		if f.needs != engine.DetailsNone {
+			apply(f)
+		}
+	}
+
+	return prs, rejected, nil
+}
+
+// loadDetails loads the given details of pull requests from their instances.
+func (s *Service) loadDetails(ctx context.Context, prs []git.PullRequest, details engine.Details, progress func(done, total int)) ([]git.PullRequest, error) {
+	ctx, span := otel.GetTracerProvider().Tracer("service").
+		Start(ctx, fmt.Sprintf("load details of %d PRs", len(prs)))
+	defer span.End()
+
+	if progress == nil {
+		progress = func(int, int) {}
+	}
+
+	var done atomic.Int64
+	progress(0, len(prs))
+
+	ewg, ctx := errgroup.WithContext(ctx)
+	ewg.SetLimit(detailsConcurrency)
+	for idx := range prs {
+		idx := idx
+		ewg.Go(func() error {
+			pr := prs[idx]
+			if details != engine.DetailsNone {
+				var err error
+				if pr, err = s.instances[pr.Instance].eng.LoadDetails(ctx, pr, details); err != nil {
+					return fmt.Errorf("load details of %s: %w", pr.URL, err)
+				}
+			}
+
+			pr.LastActivityAt = pr.LatestActivity()
+			prs[idx] = pr
+			progress(int(done.Add(1)), len(prs))
+			return nil
+		})
+	}
+
+	if err := ewg.Wait(); err != nil {
+		span.SetAttributes(attribute.String("err", err.Error()))
+		return nil, err
+	}
+
 	return prs, nil
 }
 
-func (s *Service) listPRs(ctx context.Context, req ListPRsRequest) ([]git.PullRequest, error) {
+func (s *Service) listPRs(ctx context.Context, req engine.ListPRsRequest) ([]git.PullRequest, error) {
 	ctx, span := otel.GetTracerProvider().Tracer("service").
 		Start(ctx, fmt.Sprintf("list PRs from engine"))
 	defer span.End()
 
-	listFn := s.eng.ListPullRequests
-	if req.Pagination.Empty() {
-		listFn = func(ctx context.Context, req engine.ListPRsRequest) ([]git.PullRequest, error) {
-			req.Pagination.PerPage = 100
-			return misc.ListAll(1, func(page int) ([]git.PullRequest, error) {
-				req.Pagination.Page = page
-				return s.eng.ListPullRequests(ctx, req)
-			})
-		}
+	var (
+		prs []git.PullRequest
+		mu  sync.Mutex
+	)
+
+	ewg, ctx := errgroup.WithContext(ctx)
+	for name, inst := range s.instances {
+		name, inst := name, inst
+		ewg.Go(func() error {
+			listFn := inst.eng.ListPullRequests
+			if req.Pagination.Empty() {
+				listFn = func(ctx context.Context, req engine.ListPRsRequest) ([]git.PullRequest, error) {
+					req.Pagination.PerPage = 100
+					return misc.ListAll(1, func(page int) ([]git.PullRequest, error) {
+						req.Pagination.Page = page
+						return inst.eng.ListPullRequests(ctx, req)
+					})
+				}
+			}
+
+			instPRs, err := listFn(ctx, req)
+			if err != nil {
+				return fmt.Errorf("list pull requests at %s: %w", name, err)
+			}
+
+			for idx := range instPRs {
+				instPRs[idx].Instance = name
+			}
+
+			mu.Lock()
+			prs = append(prs, instPRs...)
+			mu.Unlock()
+			return nil
+		})
 	}
 
-	prs, err := listFn(ctx, req.ListPRsRequest)
+	err := ewg.Wait()
+
+	// results of different instances are merged, and some engines don't
+	// sort them at all, e.g. gitea, so we need to restore the order
+	engine.SortPullRequests(prs, req.Sort)
 
 	b, marshalErr := json.Marshal(prs)
 	if marshalErr != nil {
@@ -169,9 +484,45 @@ func (s *Service) listPRs(ctx context.Context, req ListPRsRequest) ([]git.PullRe
 	return prs, err
 }
 
-// Approve approves the pull request.
-func (s *Service) Approve(ctx context.Context, projectID string, number int) error {
-	return s.eng.Approve(ctx, projectID, number)
+// pushDown makes a request to engines with the criteria, that they are able to
+// apply on their side. Criteria with several values are left for the service,
+// as APIs don't support alternatives. All criteria are applied by the service
+// anyway, as engines might ignore some of them.
+func pushDown(req ListPRsRequest) engine.ListPRsRequest {
+	ereq := req.ListPRsRequest
+	ereq.Reviewer = req.Reviewer
+	ereq.Assignee = req.Assignee
+	ereq.Group = req.Group
+	ereq.Search = req.Search
+	ereq.TargetBranch = req.TargetBranch
+
+	if len(req.Authors.Include) == 1 {
+		ereq.Author = req.Authors.Include[0]
+	}
+
+	if len(req.ProjectPaths.Include) == 1 {
+		ereq.Project = req.ProjectPaths.Include[0]
+	}
+
+	now := time.Now()
+	if req.CreatedWithin > 0 {
+		ereq.CreatedAfter = now.Add(-req.CreatedWithin)
+	}
+
+	if req.UpdatedWithin > 0 {
+		ereq.UpdatedAfter = now.Add(-req.UpdatedWithin)
+	}
+
+	return ereq
+}
+
+// Approve approves the pull request at the given instance.
+func (s *Service) Approve(ctx context.Context, instance, projectID string, number int) error {
+	inst, ok := s.instances[instance]
+	if !ok {
+		return fmt.Errorf("unknown instance %q", instance)
+	}
+	return inst.eng.Approve(ctx, projectID, number)
 }
 
 //go:generate gowrap gen -g -p . -i tracingService -t opentelemetry -o service_trace_gen.go
@@ -179,5 +530,7 @@ func (s *Service) Approve(ctx context.Context, projectID string, number int) err
 // tracingService defines a list of Service methods to generate a tracing wrapper.
 type tracingService interface {
 	ListPullRequests(ctx context.Context, req ListPRsRequest) ([]git.PullRequest, error)
-	Approve(ctx context.Context, pID string, prNum int) error
+	ListChangedPullRequests(ctx context.Context, req ListPRsRequest, since time.Time) (matched, rejected []git.PullRequest, err error)
+	Approve(ctx context.Context, instance, pID string, prNum int) error
+	CurrentUser(instance string) git.User
 }
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"github.com/Semior001/glmrl/pkg/git"
+	"github.com/Semior001/glmrl/pkg/git/engine"
+	"github.com/Semior001/glmrl/pkg/misc"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeEngine lists the given pull requests at the first page, or the given
+// pages, if set, and records the details, requested for each of them.
+type fakeEngine struct {
+	engine.Interface
+	prs   []git.PullRequest
+	pages [][]git.PullRequest
+
+	mu    sync.Mutex
+	calls map[string]engine.Details // details, requested by URLs of pull requests
+}
+
+func (e *fakeEngine) GetCurrentUser(context.Context) (git.User, error) {
+	return git.User{Username: "me"}, nil
+}
+
+func (e *fakeEngine) ListPullRequests(_ context.Context, req engine.ListPRsRequest) ([]git.PullRequest, error) {
+	if e.pages != nil {
+		if req.Pagination.Page > len(e.pages) {
+			return nil, nil
+		}
+		return append([]git.PullRequest(nil), e.pages[req.Pagination.Page-1]...), nil
+	}
+
+	if req.Pagination.Page > 1 {
+		return nil, nil
+	}
+	return append([]git.PullRequest(nil), e.prs...), nil
+}
+
+func (e *fakeEngine) LoadDetails(_ context.Context, pr git.PullRequest, details engine.Details) (git.PullRequest, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.calls == nil {
+		e.calls = map[string]engine.Details{}
+	}
+	e.calls[pr.URL] = details
+
+	if details.Has(engine.DetailsApprovals) && pr.Number == 1 {
+		pr.Approvals.By = []git.User{{Username: "alice"}}
+	}
+	return pr, nil
+}
+
+func TestService_ListPullRequests_details(t *testing.T) {
+	prs := []git.PullRequest{
+		{URL: "1", Number: 1, Title: "x1", State: git.StateOpen, Labels: []string{"a"}},
+		{URL: "2", Number: 2, Title: "y2", State: git.StateOpen, Labels: []string{"a", "b"}},
+		{URL: "3", Number: 3, Title: "x3", State: git.StateOpen},
+		{URL: "4", Number: 4, Title: "x4", State: git.StateDraft, Labels: []string{"a"}},
+	}
+
+	where := func(src string) *Where {
+		w, err := ParseWhere(src)
+		require.NoError(t, err)
+		return w
+	}
+
+	tests := []struct {
+		name      string
+		req       ListPRsRequest
+		wantURLs  []string
+		wantCalls map[string]engine.Details
+	}{
+		{
+			name:     "cheap filters don't load details",
+			req:      ListPRsRequest{ListPRsRequest: engine.ListPRsRequest{Labels: misc.Filter[string]{Include: []string{"a"}}}},
+			wantURLs: []string{"1", "2"},
+		},
+		{
+			name: "details are loaded only for pull requests, that passed cheap filters",
+			req: ListPRsRequest{
+				ListPRsRequest: engine.ListPRsRequest{Labels: misc.Filter[string]{Include: []string{"a"}}},
+				ApprovedByMe:   lo.ToPtr(false),
+			},
+			wantURLs:  []string{"1", "2"},
+			wantCalls: map[string]engine.Details{"1": engine.DetailsApprovals, "2": engine.DetailsApprovals},
+		},
+		{
+			name: "requested details are loaded along with the ones, needed by filters",
+			req: ListPRsRequest{
+				ListPRsRequest: engine.ListPRsRequest{Labels: misc.Filter[string]{Exclude: []string{"a"}}},
+				Details:        engine.DetailsDiff,
+				MaxLines:       10,
+			},
+			wantURLs:  []string{"3"},
+			wantCalls: map[string]engine.Details{"3": engine.DetailsDiff},
+		},
+		{
+			name:     "where over listed fields doesn't load details",
+			req:      ListPRsRequest{Where: where(`title contains "x"`)},
+			wantURLs: []string{"1", "3"},
+		},
+		{
+			name:     "where loads only the details of its fields",
+			req:      ListPRsRequest{Where: where(`title contains "x" && approvals.count > 0`)},
+			wantURLs: []string{"1"},
+			wantCalls: map[string]engine.Details{
+				"1": engine.DetailsApprovals,
+				"2": engine.DetailsApprovals,
+				"3": engine.DetailsApprovals,
+			},
+		},
+		{
+			name:     "where with several details",
+			req:      ListPRsRequest{Where: where(`threads.total == 0 && diff.lines < 10 && number == 2`)},
+			wantURLs: []string{"2"},
+			wantCalls: map[string]engine.Details{
+				"1": engine.DetailsDiscussions | engine.DetailsDiff,
+				"2": engine.DetailsDiscussions | engine.DetailsDiff,
+				"3": engine.DetailsDiscussions | engine.DetailsDiff,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eng := &fakeEngine{prs: prs}
+			svc, err := NewService(context.Background(), map[string]engine.Interface{"inst": eng})
+			require.NoError(t, err)
+
+			res, err := svc.ListPullRequests(context.Background(), tt.req)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantURLs, lo.Map(res, func(pr git.PullRequest, _ int) string { return pr.URL }))
+			assert.Equal(t, tt.wantCalls, eng.calls)
+		})
+	}
+}
+
+func TestService_ListPullRequests_pushes(t *testing.T) {
+	at := func(h int) time.Time { return time.Date(2023, 1, 1, h, 0, 0, 0, time.UTC) }
+	me, bob := git.User{Username: "me"}, git.User{Username: "bob"}
+
+	prs := []git.PullRequest{
+		{URL: "approved after my push", CreatedAt: at(0), History: []git.Event{
+			{Actor: me, Timestamp: at(1), Type: git.EventTypePushed},
+			{Actor: bob, Timestamp: at(2), Type: git.EventTypeApproved},
+		}},
+		{URL: "approved before my push", CreatedAt: at(0), History: []git.Event{
+			{Actor: bob, Timestamp: at(1), Type: git.EventTypeApproved},
+			{Actor: me, Timestamp: at(2), Type: git.EventTypePushed},
+		}},
+		{URL: "pushed after my review", CreatedAt: at(0), History: []git.Event{
+			{Actor: me, Timestamp: at(1), Type: git.EventTypeApproved},
+			{Actor: bob, Timestamp: at(2), Type: git.EventTypePushed},
+		}},
+		{URL: "pushed by someone after my review", CreatedAt: at(0), History: []git.Event{
+			{Actor: me, Timestamp: at(1), Type: git.EventTypeCommented},
+			{Timestamp: at(2), Type: git.EventTypePushed},
+		}},
+	}
+
+	tests := []struct {
+		name     string
+		req      ListPRsRequest
+		wantURLs []string
+	}{
+		{
+			name:     "approved since my last push",
+			req:      ListPRsRequest{ApprovedSinceMyLastPush: true},
+			wantURLs: []string{"approved after my push", "pushed after my review"},
+		},
+		{
+			name:     "new commits since my review",
+			req:      ListPRsRequest{NewCommitsSinceMyReview: true},
+			wantURLs: []string{"pushed after my review", "pushed by someone after my review"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eng := &fakeEngine{prs: prs}
+			svc, err := NewService(context.Background(), map[string]engine.Interface{"inst": eng})
+			require.NoError(t, err)
+
+			res, err := svc.ListPullRequests(context.Background(), tt.req)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantURLs, lo.Map(res, func(pr git.PullRequest, _ int) string { return pr.URL }))
+			for _, details := range eng.calls {
+				assert.Equal(t, engine.DetailsDiscussions|engine.DetailsCommits, details,
+					"both filters must read the same pushes")
+			}
+		})
+	}
+}
+
+func TestService_ListPullRequests_sort(t *testing.T) {
+	at := func(h int) time.Time { return time.Date(2023, 1, 1, h, 0, 0, 0, time.UTC) }
+
+	// engine doesn't sort pull requests, e.g. gitea
+	eng := &fakeEngine{pages: [][]git.PullRequest{
+		{{URL: "1", CreatedAt: at(1)}, {URL: "3", CreatedAt: at(3)}},
+		{{URL: "2", CreatedAt: at(2)}},
+	}}
+	svc, err := NewService(context.Background(), map[string]engine.Interface{"inst": eng})
+	require.NoError(t, err)
+
+	res, err := svc.ListPullRequests(context.Background(), ListPRsRequest{ListPRsRequest: engine.ListPRsRequest{
+		Sort: misc.Sort{By: misc.SortByCreatedAt, Order: misc.SortOrderDesc},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3", "2", "1"}, lo.Map(res, func(pr git.PullRequest, _ int) string { return pr.URL }),
+		"pull requests of all pages must be sorted")
+}
+
+func TestPushDown(t *testing.T) {
+	base := engine.ListPRsRequest{
+		State:  git.StateOpen,
+		Labels: misc.Filter[string]{Include: []string{"a"}},
+		Sort:   misc.Sort{By: misc.SortByTitle, Order: misc.SortOrderAsc},
+	}
+
+	tests := []struct {
+		name              string
+		req               ListPRsRequest
+		want              engine.ListPRsRequest
+		wantCreatedWithin time.Duration
+		wantUpdatedWithin time.Duration
+	}{
+		{
+			name: "engine criteria are kept",
+			req:  ListPRsRequest{ListPRsRequest: base},
+			want: base,
+		},
+		{
+			name: "single-valued criteria are pushed down",
+			req: ListPRsRequest{
+				ListPRsRequest: base,
+				Reviewer:       "bob",
+				Assignee:       "carol",
+				Group:          "group",
+				Search:         "fix",
+				TargetBranch:   "main",
+				Authors:        misc.Filter[string]{Include: []string{"alice"}},
+				ProjectPaths:   misc.Filter[string]{Include: []string{"group/project"}},
+			},
+			want: func() engine.ListPRsRequest {
+				want := base
+				want.Reviewer, want.Assignee, want.Group = "bob", "carol", "group"
+				want.Search, want.TargetBranch = "fix", "main"
+				want.Author, want.Project = "alice", "group/project"
+				return want
+			}(),
+		},
+		{
+			name: "alternatives and exclusions are left for the service",
+			req: ListPRsRequest{
+				ListPRsRequest: base,
+				Authors:        misc.Filter[string]{Include: []string{"alice", "bob"}},
+				ProjectPaths:   misc.Filter[string]{Exclude: []string{"group/project"}},
+			},
+			want: base,
+		},
+		{
+			name:              "durations are turned into times",
+			req:               ListPRsRequest{ListPRsRequest: base, CreatedWithin: time.Hour, UpdatedWithin: time.Minute},
+			want:              base,
+			wantCreatedWithin: time.Hour,
+			wantUpdatedWithin: time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pushDown(tt.req)
+
+			// times depend on the current one, so they're checked separately
+			if tt.wantCreatedWithin > 0 {
+				assert.WithinDuration(t, time.Now().Add(-tt.wantCreatedWithin), got.CreatedAfter, time.Second)
+				got.CreatedAfter = time.Time{}
+			}
+			if tt.wantUpdatedWithin > 0 {
+				assert.WithinDuration(t, time.Now().Add(-tt.wantUpdatedWithin), got.UpdatedAfter, time.Second)
+				got.UpdatedAfter = time.Time{}
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -6,6 +6,7 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/Semior001/glmrl/pkg/git"
 	"go.opentelemetry.io/otel"
@@ -35,14 +36,15 @@ func NewtracingServiceWithTracing(base tracingService, instance string, spanDeco
 }
 
 // Approve implements tracingService
-func (_d tracingServiceWithTracing) Approve(ctx context.Context, pID string, prNum int) (err error) {
+func (_d tracingServiceWithTracing) Approve(ctx context.Context, instance string, pID string, prNum int) (err error) {
 	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "tracingService.Approve")
 	defer func() {
 		if _d._spanDecorator != nil {
 			_d._spanDecorator(_span, map[string]interface{}{
-				"ctx":   ctx,
-				"pID":   pID,
-				"prNum": prNum}, map[string]interface{}{
+				"ctx":      ctx,
+				"instance": instance,
+				"pID":      pID,
+				"prNum":    prNum}, map[string]interface{}{
 				"err": err})
 		} else if err != nil {
 			_span.RecordError(err)
@@ -54,7 +56,47 @@ func (_d tracingServiceWithTracing) Approve(ctx context.Context, pID string, prN
 
 		_span.End()
 	}()
-	return _d.tracingService.Approve(ctx, pID, prNum)
+	return _d.tracingService.Approve(ctx, instance, pID, prNum)
+}
+
+// CurrentUser implements tracingService
+func (_d tracingServiceWithTracing) CurrentUser(instance string) (u1 git.User) {
+	_, _span := otel.Tracer(_d._instance).Start(context.Background(), "tracingService.CurrentUser")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"instance": instance}, map[string]interface{}{
+				"u1": u1})
+		}
+
+		_span.End()
+	}()
+	return _d.tracingService.CurrentUser(instance)
+}
+
+// ListChangedPullRequests implements tracingService
+func (_d tracingServiceWithTracing) ListChangedPullRequests(ctx context.Context, req ListPRsRequest, since time.Time) (matched []git.PullRequest, rejected []git.PullRequest, err error) {
+	ctx, _span := otel.Tracer(_d._instance).Start(ctx, "tracingService.ListChangedPullRequests")
+	defer func() {
+		if _d._spanDecorator != nil {
+			_d._spanDecorator(_span, map[string]interface{}{
+				"ctx":   ctx,
+				"req":   req,
+				"since": since}, map[string]interface{}{
+				"matched":  matched,
+				"rejected": rejected,
+				"err":      err})
+		} else if err != nil {
+			_span.RecordError(err)
+			_span.SetAttributes(
+				attribute.String("event", "error"),
+				attribute.String("message", err.Error()),
+			)
+		}
+
+		_span.End()
+	}()
+	return _d.tracingService.ListChangedPullRequests(ctx, req, since)
 }
 
 // ListPullRequests implements tracingService
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"encoding/json"
+	"github.com/Semior001/glmrl/pkg/expr"
+	"github.com/Semior001/glmrl/pkg/git"
+	"github.com/Semior001/glmrl/pkg/git/engine"
+	"github.com/samber/lo"
+	"time"
+)
+
+// Where is a compiled filter expression over pull requests.
+// See WhereHelp for the list of available fields.
+type Where struct {
+	prog    *expr.Program[whereEnv]
+	details engine.Details
+}
+
+// whereEnv is a pull request with the context of the current user.
+type whereEnv struct {
+	git.PullRequest
+	me git.User
+}
+
+// ParseWhere parses and type-checks the filter expression.
+func ParseWhere(src string) (*Where, error) {
+	prog, err := expr.Compile(src, whereFields)
+	if err != nil {
+		return nil, err
+	}
+	w := &Where{prog: prog}
+	for _, name := range prog.Fields() {
+		w.details |= whereDetails[name]
+	}
+
+	return w, nil
+}
+
+// String returns the source of the expression.
+func (w *Where) String() string { return w.prog.String() }
+
+// Details returns the details of pull requests, needed by the fields of the expression.
+func (w *Where) Details() engine.Details { return w.details }
+
+// MarshalJSON marshals the expression as its source, for tracing purposes.
+func (w *Where) MarshalJSON() ([]byte, error) { return json.Marshal(w.String()) }
+
+// Match returns true if the pull request satisfies the expression.
+func (w *Where) Match(pr git.PullRequest, me git.User) bool {
+	return w.prog.Match(whereEnv{PullRequest: pr, me: me})
+}
+
+// WhereHelp returns the description of fields, available in the expressions.
+func WhereHelp() string { return expr.Help(whereFields) }
+
+func usernames(us []git.User) []string {
+	return lo.Map(us, func(u git.User, _ int) string { return u.Username })
+}
+
+// whereDetails are the details, needed by the fields, fields that
+// are filled by listing pull requests are omitted.
+var whereDetails = map[string]engine.Details{
+	"approvals.by":         engine.DetailsApprovals,
+	"approvals.count":      engine.DetailsApprovals,
+	"approvals.required":   engine.DetailsApprovals,
+	"approvals.satisfied":  engine.DetailsApprovals,
+	"rereview":             engine.DetailsDiscussions | engine.DetailsCommits,
+	"new_commits":          engine.DetailsDiscussions | engine.DetailsCommits,
+	"pipeline":             engine.DetailsPipeline,
+	"pipeline.failed_jobs": engine.DetailsPipeline,
+	"diff.files":           engine.DetailsDiff,
+	"diff.additions":       engine.DetailsDiff,
+	"diff.deletions":       engine.DetailsDiff,
+	"diff.lines":           engine.DetailsDiff,
+	"threads.total":        engine.DetailsDiscussions,
+	"threads.resolved":     engine.DetailsDiscussions,
+	"threads.unresolved":   engine.DetailsDiscussions,
+	"idle":                 engine.DetailsDiscussions | engine.DetailsCommits,
+}
+
+var whereFields = map[string]expr.Field[whereEnv]{
+	"me": {
+		Type: expr.TypeString, Description: "username of the current user at the instance",
+		Get: func(e whereEnv) any { return e.me.Username },
+	},
+	"instance": {
+		Type: expr.TypeString, Description: "name of the instance",
+		Get: func(e whereEnv) any { return e.Instance },
+	},
+	"project": {
+		Type: expr.TypeString, Description: "full path of the project",
+		Get: func(e whereEnv) any { return e.Project.FullPath },
+	},
+	"number": {
+		Type: expr.TypeInt, Description: "number of the pull request",
+		Get: func(e whereEnv) any { return e.Number },
+	},
+	"title": {
+		Type: expr.TypeString, Description: "title",
+		Get: func(e whereEnv) any { return e.Title },
+	},
+	"body": {
+		Type: expr.TypeString, Description: "description",
+		Get: func(e whereEnv) any { return e.Body },
+	},
+	"author": {
+		Type: expr.TypeString, Description: "username of the author",
+		Get: func(e whereEnv) any { return e.Author.Username },
+	},
+	"labels": {
+		Type: expr.TypeStrings, Description: "labels",
+		Get: func(e whereEnv) any { return e.Labels },
+	},
+	"state": {
+		Type: expr.TypeString, Description: "one of open, draft, closed, merged",
+		Get: func(e whereEnv) any { return string(e.State) },
+	},
+	"draft": {
+		Type: expr.TypeBool, Description: "whether the pull request is a draft",
+		Get: func(e whereEnv) any { return e.State == git.StateDraft },
+	},
+	"source_branch": {
+		Type: expr.TypeString, Description: "source branch",
+		Get: func(e whereEnv) any { return e.SourceBranch },
+	},
+	"target_branch": {
+		Type: expr.TypeString, Description: "target branch",
+		Get: func(e whereEnv) any { return e.TargetBranch },
+	},
+	"assignees": {
+		Type: expr.TypeStrings, Description: "usernames of assignees",
+		Get: func(e whereEnv) any { return usernames(e.Assignees) },
+	},
+	"reviewers": {
+		Type: expr.TypeStrings, Description: "usernames of requested reviewers",
+		Get: func(e whereEnv) any { return usernames(e.Approvals.RequestedFrom) },
+	},
+	"approvals.by": {
+		Type: expr.TypeStrings, Description: "usernames of approvers",
+		Get: func(e whereEnv) any { return usernames(e.Approvals.By) },
+	},
+	"approvals.count": {
+		Type: expr.TypeInt, Description: "number of approvals",
+		Get: func(e whereEnv) any { return len(e.Approvals.By) },
+	},
+	"approvals.required": {
+		Type: expr.TypeInt, Description: "number of required approvals",
+		Get: func(e whereEnv) any { return e.Approvals.Required },
+	},
+	"approvals.satisfied": {
+		Type: expr.TypeBool, Description: "whether approval rules are satisfied",
+		Get: func(e whereEnv) any { return e.Approvals.SatisfiesRules },
+	},
+	"rereview": {
+		Type: expr.TypeBool, Description: "whether I approved it, but new commits were pushed since then",
+		Get: func(e whereEnv) any { return e.NeedsReReview(e.me) },
+	},
+	"new_commits": {
+		Type: expr.TypeBool, Description: "whether new commits were pushed after my last approval or comment",
+		Get: func(e whereEnv) any {
+			review, ok := e.LastReviewBy(e.me)
+			return ok && e.ChangedSince(review.Timestamp)
+		},
+	},
+	"pipeline": {
+		Type: expr.TypeString, Description: "status of the latest pipeline, e.g. success, failed, running, empty if none",
+		Get: func(e whereEnv) any { return string(e.Pipeline.Status) },
+	},
+	"pipeline.failed_jobs": {
+		Type: expr.TypeStrings, Description: "names of the failed jobs of the latest pipeline",
+		Get: func(e whereEnv) any { return e.Pipeline.FailedJobs },
+	},
+	"mergeability.conflicts": {
+		Type: expr.TypeBool, Description: "whether the source branch has conflicts with the target one",
+		Get: func(e whereEnv) any { return e.Mergeability.HasConflicts },
+	},
+	"mergeability.needs_rebase": {
+		Type: expr.TypeBool, Description: "whether the source branch is behind the target one and must be rebased",
+		Get: func(e whereEnv) any { return e.Mergeability.NeedsRebase },
+	},
+	"mergeability.blocked_by_discussions": {
+		Type: expr.TypeBool, Description: "whether merge is blocked by unresolved discussions",
+		Get: func(e whereEnv) any { return e.Mergeability.BlockedByDiscussions },
+	},
+	"mergeability.status": {
+		Type: expr.TypeString, Description: "engine-specific detailed merge status, e.g. mergeable, ci_must_pass",
+		Get: func(e whereEnv) any { return e.Mergeability.Status },
+	},
+	"diff.files": {
+		Type: expr.TypeInt, Description: "number of changed files",
+		Get: func(e whereEnv) any { return e.Diff.Files },
+	},
+	"diff.additions": {
+		Type: expr.TypeInt, Description: "number of added lines",
+		Get: func(e whereEnv) any { return e.Diff.Additions },
+	},
+	"diff.deletions": {
+		Type: expr.TypeInt, Description: "number of deleted lines",
+		Get: func(e whereEnv) any { return e.Diff.Deletions },
+	},
+	"diff.lines": {
+		Type: expr.TypeInt, Description: "number of changed lines",
+		Get: func(e whereEnv) any { return e.Diff.Lines() },
+	},
+	"threads.total": {
+		Type: expr.TypeInt, Description: "number of threads",
+		Get: func(e whereEnv) any { return len(e.Threads) },
+	},
+	"threads.resolved": {
+		Type: expr.TypeInt, Description: "number of resolved threads",
+		Get: func(e whereEnv) any { return lo.CountBy(e.Threads, func(t git.Comment) bool { return t.Resolved }) },
+	},
+	"threads.unresolved": {
+		Type: expr.TypeInt, Description: "number of unresolved threads",
+		Get: func(e whereEnv) any { return lo.CountBy(e.Threads, func(t git.Comment) bool { return !t.Resolved }) },
+	},
+	"updated_ago": {
+		Type: expr.TypeDuration, Description: "time since the last update",
+		Get: func(e whereEnv) any { return time.Since(e.UpdatedAt) },
+	},
+	"idle": {
+		Type: expr.TypeDuration, Description: "time since the last activity of users",
+		Get: func(e whereEnv) any { return time.Since(e.LastActivityAt) },
+	},
+	"age": {
+		Type: expr.TypeDuration, Description: "time since creation",
+		Get: func(e whereEnv) any { return time.Since(e.CreatedAt) },
+	},
+}
@@ -0,0 +1,217 @@
+package tui
+
+import (
+	"fmt"
+	"github.com/Semior001/glmrl/pkg/git"
+	"github.com/Semior001/glmrl/pkg/git/engine"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samber/lo"
+	"strings"
+	"time"
+)
+
+const detailsTimeFormat = "2006-01-02 15:04"
+
+var (
+	detailsHeaderStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFA500"))
+	detailsKeyStyle    = lipgloss.NewStyle().Bold(true)
+	detailsMutedStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+)
+
+// DetailsViewDetails are the details of pull requests, shown by the details view.
+const DetailsViewDetails = engine.DetailsAll
+
+// PRDetails is a scrollable view of a single pull request
+// with its description, threads and history.
+type PRDetails struct {
+	pr       git.PullRequest
+	viewport viewport.Model
+}
+
+// NewPRDetails makes a new view of the pull request with the given size.
+func NewPRDetails(pr git.PullRequest, width, height int) *PRDetails {
+	d := &PRDetails{pr: pr, viewport: viewport.New(width, height)}
+	d.viewport.SetContent(d.render(width))
+	return d
+}
+
+// Init does nothing.
+func (d *PRDetails) Init() tea.Cmd { return nil }
+
+// Update scrolls the view and adjusts it to the window size.
+func (d *PRDetails) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.WindowSizeMsg); ok {
+		d.Resize(msg.Width, msg.Height)
+		return d, nil
+	}
+
+	var cmd tea.Cmd
+	d.viewport, cmd = d.viewport.Update(msg)
+	return d, cmd
+}
+
+// Resize sets the size of the view.
+func (d *PRDetails) Resize(width, height int) {
+	d.viewport.Width, d.viewport.Height = width, height
+	d.viewport.SetContent(d.render(width))
+}
+
+// View renders the view.
+func (d *PRDetails) View() string {
+	return lipgloss.JoinVertical(lipgloss.Left,
+		d.viewport.View(),
+		detailsMutedStyle.Render(fmt.Sprintf(" ↑/↓: scroll, esc: back to the list (%3.f%%)", d.viewport.ScrollPercent()*100)),
+	)
+}
+
+func (d *PRDetails) render(width int) string {
+	pr := d.pr
+	block := lipgloss.NewStyle().Width(width).PaddingLeft(1)
+
+	field := func(name, value string) string {
+		return detailsKeyStyle.Render(name+": ") + lo.Ternary(value == "", detailsMutedStyle.Render("none"), value)
+	}
+
+	approvals := fmt.Sprintf("%d/%d (%s)", len(pr.Approvals.By), pr.Approvals.Required, checkmark(pr.Approvals.SatisfiesRules))
+	if len(pr.Approvals.By) > 0 {
+		approvals += " by " + strings.Join(usernames(pr.Approvals.By), ", ")
+	}
+
+	pipeline := string(pr.Pipeline.Status)
+	if pr.Pipeline.Status != git.PipelineStatusNone && !pr.Pipeline.FinishedAt.IsZero() {
+		pipeline += ", finished at " + pr.Pipeline.FinishedAt.Format(detailsTimeFormat)
+	}
+	if len(pr.Pipeline.FailedJobs) > 0 {
+		pipeline += ", failed jobs: " + strings.Join(pr.Pipeline.FailedJobs, ", ")
+	}
+
+	mergeability := pr.Mergeability.Status
+	for _, flag := range []struct {
+		set  bool
+		text string
+	}{
+		{pr.Mergeability.HasConflicts, "has conflicts"},
+		{pr.Mergeability.NeedsRebase, "needs rebase"},
+		{pr.Mergeability.BlockedByDiscussions, "blocked by discussions"},
+	} {
+		if flag.set {
+			mergeability += ", " + flag.text
+		}
+	}
+	mergeability = strings.TrimPrefix(mergeability, ", ")
+
+	// last activity is set by the service, so it's computed here for
+	// pull requests, that didn't pass through it, and omitted if unknown
+	lastActivity := ""
+	if at := lo.Ternary(pr.LastActivityAt.IsZero(), pr.LatestActivity(), pr.LastActivityAt); !at.IsZero() {
+		lastActivity = fmt.Sprintf("%s (%s ago)", at.Format(detailsTimeFormat), humanizeDuration(time.Since(at)))
+	}
+
+	sections := []string{
+		detailsHeaderStyle.Render(fmt.Sprintf("%s !%d: %s", pr.Project.FullPath, pr.Number, pr.Title)),
+		pr.URL,
+		"",
+		field("State", string(pr.State)),
+		field("Author", pr.Author.Username),
+		field("Created at", pr.CreatedAt.Format(detailsTimeFormat)),
+		field("Updated at", pr.UpdatedAt.Format(detailsTimeFormat)),
+		field("Last activity", lastActivity),
+		field("Branches", fmt.Sprintf("%s → %s", pr.SourceBranch, pr.TargetBranch)),
+		field("Labels", strings.Join(pr.Labels, ", ")),
+		field("Assignees", strings.Join(usernames(pr.Assignees), ", ")),
+		field("Reviewers", strings.Join(usernames(pr.Approvals.RequestedFrom), ", ")),
+		field("Approvals", approvals),
+		field("Changes", fmt.Sprintf("%d files, +%d/-%d lines", pr.Diff.Files, pr.Diff.Additions, pr.Diff.Deletions)),
+		field("Pipeline", pipeline),
+		field("Merge status", mergeability),
+		"",
+		detailsHeaderStyle.Render("Description"),
+		lo.Ternary(strings.TrimSpace(pr.Body) == "", detailsMutedStyle.Render("no description"), pr.Body),
+		"",
+		detailsHeaderStyle.Render(fmt.Sprintf("Threads (%d/%d resolved)",
+			lo.CountBy(pr.Threads, func(t git.Comment) bool { return t.Resolved }), len(pr.Threads))),
+		d.renderThreads(),
+		"",
+		detailsHeaderStyle.Render("History"),
+		d.renderHistory(),
+	}
+
+	return block.Render(strings.Join(sections, "\n"))
+}
+
+func (d *PRDetails) renderThreads() string {
+	if len(d.pr.Threads) == 0 {
+		return detailsMutedStyle.Render("no threads")
+	}
+
+	var lines []string
+	for _, thread := range d.pr.Threads {
+		status := "unresolved, waiting for " + threadTurn(d.pr, thread)
+		if thread.Resolved {
+			status = "resolved"
+		}
+
+		location := "general discussion"
+		if thread.File != "" {
+			location = fmt.Sprintf("%s:%d", thread.File, thread.Line)
+		}
+
+		lines = append(lines, "", fmt.Sprintf("%s %s, %s", checkmark(thread.Resolved), location, status))
+
+		for c := &thread; c != nil; c = c.Child {
+			lines = append(lines,
+				detailsKeyStyle.Render(fmt.Sprintf("  %s at %s:", c.Author.Username, c.CreatedAt.Format(detailsTimeFormat))),
+				indent(c.Body, "    "),
+			)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (d *PRDetails) renderHistory() string {
+	if len(d.pr.History) == 0 {
+		return detailsMutedStyle.Render("no events")
+	}
+
+	lines := make([]string, len(d.pr.History))
+	for idx, ev := range d.pr.History {
+		// engines don't report the actors of some events, e.g. pushes of commits
+		actor := lo.Ternary(ev.Actor.Username == "", detailsMutedStyle.Render("someone"), ev.Actor.Username)
+		line := fmt.Sprintf("%s  %s %s", ev.Timestamp.Format(detailsTimeFormat), actor, ev.Type)
+		if ev.ObjectType != "" {
+			line += detailsMutedStyle.Render(fmt.Sprintf(" (%s %s)", ev.ObjectType, ev.ObjectID))
+		}
+		if ev.Approximate {
+			line += detailsMutedStyle.Render(" (approximate time)")
+		}
+		lines[idx] = line
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// threadTurn returns the username of the one, who is expected to act in the
+// thread next: the author of the pull request replies to the reviewer's
+// comment, while the reviewer either resolves the thread, or replies back.
+func threadTurn(pr git.PullRequest, thread git.Comment) string {
+	last := thread.Last()
+	switch {
+	case last.Author.Username != pr.Author.Username:
+		return pr.Author.Username
+	case thread.Author.Username != pr.Author.Username:
+		return thread.Author.Username
+	default:
+		return "reviewers"
+	}
+}
+
+func indent(s, prefix string) string {
+	return prefix + strings.ReplaceAll(strings.TrimSpace(s), "\n", "\n"+prefix)
+}
+
+func usernames(us []git.User) []string {
+	return lo.Map(us, func(u git.User, _ int) string { return u.Username })
+}
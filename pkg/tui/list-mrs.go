@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/Semior001/glmrl/pkg/git"
+	"github.com/Semior001/glmrl/pkg/git/engine"
 	"github.com/Semior001/glmrl/pkg/service"
 	"github.com/Semior001/glmrl/pkg/tui/teax"
 	"github.com/atotto/clipboard"
@@ -17,6 +18,7 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"log"
+	"slices"
 	"strconv"
 	"time"
 )
@@ -26,54 +28,146 @@ type ListPR struct {
 	ctx context.Context
 	ListPRParams
 	tea.Model
+
+	tables        []*teax.RefreshingDataTable[git.PullRequest]
+	tabs          *teax.Tabs
+	width, height int
+	details       *PRDetails // shown instead of the table, if set
 }
 
 // PRStore is a store of pull requests.
 type PRStore interface {
 	ListPullRequests(ctx context.Context, req service.ListPRsRequest) ([]git.PullRequest, error)
-	Approve(ctx context.Context, projectID string, prNumber int) error
+	ListChangedPullRequests(ctx context.Context, req service.ListPRsRequest, since time.Time) (matched, rejected []git.PullRequest, err error)
+	Approve(ctx context.Context, instance, projectID string, prNumber int) error
+	CurrentUser(instance string) git.User
 }
 
 // ListPRParams are the parameters to initialize a ListPR TUI.
 type ListPRParams struct {
 	Service      PRStore
 	Request      service.ListPRsRequest
+	Tabs         []ListPRTab // if set, each tab is shown in its own table and Request is ignored
 	OpenOnEnter  bool
 	PollInterval time.Duration
 	Version      string
+	ShowInstance bool // show the column with the name of the instance
+	Sizes        SizeThresholds
+}
+
+// SizeThresholds are the upper bounds of changed lines for S, M and L
+// sizes of pull requests, bigger ones are XL.
+type SizeThresholds struct {
+	S, M, L int
+}
+
+// Size returns the size bucket for the given number of changed lines.
+func (t SizeThresholds) Size(lines int) string {
+	switch {
+	case lines <= t.S:
+		return "S"
+	case lines <= t.M:
+		return "M"
+	case lines <= t.L:
+		return "L"
+	default:
+		return "XL"
+	}
+}
+
+// ListPRTab is a tab with its own request and poll interval.
+type ListPRTab struct {
+	Name         string
+	Request      service.ListPRsRequest
+	PollInterval time.Duration
 }
 
 // NewListPR returns a new ListPR TUI.
 func NewListPR(ctx context.Context, params ListPRParams) (tea.Model, error) {
 	a := &ListPR{ctx: ctx, ListPRParams: params}
-	tbl, err := teax.NewRefreshingDataTable(teax.RefreshingDataTableParams[git.PullRequest]{
-		Columns:        ListPRColumns,
-		Actor:          a,
-		PollInterval:   params.PollInterval,
-		BorrowedHeight: 1, // version line
-	})
-	if err != nil {
-		return nil, fmt.Errorf("new table: %w", err)
+
+	// the shared columns are copied, as appending might write into their backing array
+	prCols := append(slices.Clone(ListPRColumns), SizeColumn(params.Sizes), ReReviewColumn(params.Service.CurrentUser))
+	if params.ShowInstance {
+		prCols = append([]PRColumn{prCols[0], InstanceColumn}, prCols[1:]...)
+	}
+
+	// only the details, that are shown, are loaded
+	details := DetailsViewDetails
+	cols := make([]teax.Column[git.PullRequest], len(prCols))
+	for idx, col := range prCols {
+		cols[idx] = col.Column
+		details |= col.Details
+	}
+
+	tabs := params.Tabs
+	borrowedHeight := 1 // version line
+	if len(tabs) == 0 {
+		tabs = []ListPRTab{{Request: params.Request, PollInterval: params.PollInterval}}
+	} else {
+		borrowedHeight++ // tab bar
+	}
+
+	// tables load the data in background, once the program starts
+	tables := make([]*teax.RefreshingDataTable[git.PullRequest], len(tabs))
+	for idx, tab := range tabs {
+		tab.Request.Details |= details
+		tbl, err := teax.NewRefreshingDataTable(teax.RefreshingDataTableParams[git.PullRequest]{
+			Columns:        cols,
+			Actor:          &prActor{l: a, name: tab.Name, req: tab.Request},
+			PollInterval:   tab.PollInterval,
+			BorrowedHeight: borrowedHeight,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("new table %q: %w", tab.Name, err)
+		}
+		tbl.Focus()
+		tables[idx] = tbl
 	}
-	tbl.Focus()
-	a.Model = tbl
+
+	a.tables = tables
+	if len(params.Tabs) == 0 {
+		a.Model = tables[0]
+		return a, nil
+	}
+
+	a.tabs = teax.NewTabs(lo.Map(tables, func(tbl *teax.RefreshingDataTable[git.PullRequest], idx int) teax.Tab {
+		name := tabs[idx].Name
+		return teax.Tab{
+			Title: func() string { return fmt.Sprintf("%s (%d)", name, tbl.Len()) },
+			Model: tbl,
+		}
+	}))
+	a.Model = a.tabs
 	return a, nil
 }
 
+// prActor loads and acts on the merge requests of a single table.
+type prActor struct {
+	l    *ListPR
+	name string
+	req  service.ListPRsRequest
+}
+
 // Load loads the merge requests.
-func (l *ListPR) Load() ([]git.PullRequest, error) {
-	ctx := l.ctx
+func (a *prActor) Load(progress teax.Progress) ([]git.PullRequest, error) {
+	ctx := a.l.ctx
 
-	b, err := json.Marshal(l.Request)
+	b, err := json.Marshal(a.req)
 	if err != nil {
 		b = []byte(fmt.Sprintf("failed to marshal: %v", err))
 	}
 
 	ctx, span := otel.GetTracerProvider().Tracer("tui").
-		Start(ctx, "ListPR.Load", trace.WithAttributes(attribute.String("request", string(b))))
+		Start(ctx, "ListPR.Load", trace.WithAttributes(
+			attribute.String("tab", a.name),
+			attribute.String("request", string(b)),
+		))
 	defer span.End()
 
-	prs, err := l.Service.ListPullRequests(ctx, l.Request)
+	req := a.req
+	req.Progress = progress
+	prs, err := a.l.Service.ListPullRequests(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("list merge requests: %w", err)
 	}
@@ -81,38 +175,116 @@ func (l *ListPR) Load() ([]git.PullRequest, error) {
 	return prs, nil
 }
 
+// Key returns the unique key of the merge request.
+func (a *prActor) Key(pr git.PullRequest) string { return pr.URL }
+
+// LoadChanged loads the merge requests, updated since the given time.
+func (a *prActor) LoadChanged(since time.Time, progress teax.Progress) (changed []git.PullRequest, removed []string, err error) {
+	ctx, span := otel.GetTracerProvider().Tracer("tui").
+		Start(a.l.ctx, "ListPR.LoadChanged", trace.WithAttributes(
+			attribute.String("tab", a.name),
+			attribute.String("since", since.String()),
+		))
+	defer span.End()
+
+	req := a.req
+	req.Progress = progress
+	changed, rejected, err := a.l.Service.ListChangedPullRequests(ctx, req, since)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list changed merge requests: %w", err)
+	}
+
+	return changed, lo.Map(rejected, func(pr git.PullRequest, _ int) string { return a.Key(pr) }), nil
+}
+
 // OnKey reacts on user's key presses.
-func (l *ListPR) OnKey(key string, _ int, pr git.PullRequest) (reload bool, err error) {
+func (a *prActor) OnKey(key string, _ int, pr git.PullRequest) teax.KeyResult {
 	switch key {
 	case "enter":
-		if l.OpenOnEnter {
-			if err = browser.OpenURL(pr.URL); err != nil {
-				return false, fmt.Errorf("open URL %q: %w", pr.URL, err)
+		if a.l.OpenOnEnter {
+			if err := browser.OpenURL(pr.URL); err != nil {
+				return teax.KeyResult{Message: teax.Errorf("open URL %q: %v", pr.URL, err)}
 			}
-			return false, nil
+			return teax.KeyResult{Message: teax.Infof("opened %s", pr.URL)}
 		}
-		if err = clipboard.WriteAll(pr.URL); err != nil {
-			return false, fmt.Errorf("copy URL to clipboard: %w", err)
+		if err := clipboard.WriteAll(pr.URL); err != nil {
+			return teax.KeyResult{Message: teax.Errorf("copy URL to clipboard: %v", err)}
 		}
-		return false, nil
+		return teax.KeyResult{Message: teax.Infof("copied %s", pr.URL)}
 	case "a", "ф":
-		if err = l.Service.Approve(l.ctx, pr.Project.ID, pr.Number); err != nil {
-			return false, fmt.Errorf("approve PR: %w", err)
+		if err := a.l.Service.Approve(a.l.ctx, pr.Instance, pr.Project.ID, pr.Number); err != nil {
+			return teax.KeyResult{Message: teax.Errorf("approve %s !%d: %v", pr.Project.FullPath, pr.Number, err)}
 		}
 
-		// we hide only if filter "do not show PRs that are approved by me" is on
-		return l.Request.ApprovedByMe == lo.ToPtr(false), nil
+		return teax.KeyResult{
+			// we hide only if filter "do not show PRs that are approved by me" is on
+			Hide:    a.req.ApprovedByMe != nil && !*a.req.ApprovedByMe,
+			Message: teax.Infof("approved %s !%d", pr.Project.FullPath, pr.Number),
+		}
 	default:
-		return false, nil
+		return teax.KeyResult{}
 	}
 }
 
 // Update updates the model.
 func (l *ListPR) Update(msg tea.Msg) (_ tea.Model, cmd tea.Cmd) {
+	if msg, ok := msg.(tea.WindowSizeMsg); ok {
+		l.width, l.height = msg.Width, msg.Height
+		if l.details != nil {
+			l.details.Resize(l.detailsSize())
+		}
+	}
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		if l.details != nil {
+			switch msg.String() {
+			case "ctrl+c", "c+ctrl":
+				return l, tea.Quit
+			case "esc", "q", "й", "v", "м":
+				l.details = nil
+				return l, tea.ClearScreen
+			}
+
+			_, cmd = l.details.Update(msg)
+			return l, cmd
+		}
+
+		// keys of the search query go to the table
+		if l.active().Searching() {
+			l.Model, cmd = l.Model.Update(msg)
+			return l, cmd
+		}
+
+		if msg.String() == "v" || msg.String() == "м" {
+			if pr, ok := l.selected(); ok {
+				l.details = NewPRDetails(pr, 0, 0)
+				l.details.Resize(l.detailsSize())
+				return l, tea.ClearScreen
+			}
+			return l, nil
+		}
+	}
+
+	// tables keep polling, even if the details are shown
 	l.Model, cmd = l.Model.Update(msg)
 	return l, cmd
 }
 
+// active returns the table of the active tab.
+func (l *ListPR) active() *teax.RefreshingDataTable[git.PullRequest] {
+	if l.tabs != nil {
+		return l.tables[l.tabs.Active()]
+	}
+	return l.tables[0]
+}
+
+// selected returns the pull request under the cursor in the active table.
+func (l *ListPR) selected() (git.PullRequest, bool) { return l.active().Selected() }
+
+func (l *ListPR) detailsSize() (width, height int) {
+	return l.width, l.height - 2 // version line and the status line of the details
+}
+
 func (l *ListPR) controlView() string {
 	action := "open"
 	if !l.OpenOnEnter {
@@ -123,11 +295,16 @@ func (l *ListPR) controlView() string {
 		MarginLeft(1).
 		Bold(true).
 		Foreground(lipgloss.NoColor{}).
-		Render(fmt.Sprintf("↑/↓: scroll, enter: %s, r: reload, a: instant approve, q/ctrl+c: quit", action))
+		Render(fmt.Sprintf("↑/↓: scroll, %senter: %s, v: details, /: search, s/S: sort, r: reload, a: instant approve, q/ctrl+c: quit",
+			lo.Ternary(len(l.Tabs) > 0, "tab/shift+tab: switch tab, ", ""), action))
 }
 
 // View adds the version to the table view.
 func (l *ListPR) View() string {
+	if l.details != nil {
+		return lipgloss.JoinVertical(lipgloss.Top, Version(l.Version), l.details.View())
+	}
+
 	return lipgloss.JoinVertical(lipgloss.Top,
 		lipgloss.JoinHorizontal(lipgloss.Left, Version(l.Version), l.controlView()),
 		l.Model.View())
@@ -140,29 +317,73 @@ func (w loggingWriter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
+// PRColumn is a column of the table of pull requests.
+type PRColumn struct {
+	teax.Column[git.PullRequest]
+	Details engine.Details // details of pull requests, needed to render the column
+}
+
+// InstanceColumn shows the name of the instance, the pull request was loaded from.
+var InstanceColumn = PRColumn{Column: teax.Column[git.PullRequest]{
+	Column:  table.Column{Title: "Instance", Width: 3},
+	Extract: func(pr git.PullRequest) string { return pr.Instance },
+}}
+
+// SizeColumn shows the size bucket of the pull request.
+func SizeColumn(t SizeThresholds) PRColumn {
+	return PRColumn{Details: engine.DetailsDiff, Column: teax.Column[git.PullRequest]{
+		Column: table.Column{Title: "Size", Width: 2},
+		// the column is narrow, changed lines are shown in the details
+		Extract: func(pr git.PullRequest) string { return t.Size(pr.Diff.Lines()) },
+		Less:    func(a, b git.PullRequest) bool { return a.Diff.Lines() < b.Diff.Lines() },
+	}}
+}
+
+// ReReviewColumn marks pull requests, approved by the current user, that
+// received new commits after the approval.
+func ReReviewColumn(me func(instance string) git.User) PRColumn {
+	return PRColumn{Details: engine.DetailsDiscussions | engine.DetailsCommits, Column: teax.Column[git.PullRequest]{
+		Column: table.Column{Title: "Re-review", Width: 2},
+		Extract: func(pr git.PullRequest) string {
+			// the column is narrow, so the mark is short
+			return lo.Ternary(pr.NeedsReReview(me(pr.Instance)), "↻", "")
+		},
+		Less: func(a, b git.PullRequest) bool {
+			return !a.NeedsReReview(me(a.Instance)) && b.NeedsReReview(me(b.Instance))
+		},
+	}}
+}
+
 // ListPRColumns are the columns to show in the table.
-var ListPRColumns = []teax.Column[git.PullRequest]{
-	{
+var ListPRColumns = []PRColumn{
+	{Column: teax.Column[git.PullRequest]{
 		Column:  table.Column{Title: `Total: {{.Total}}`, Width: 6},
 		Extract: func(pr git.PullRequest) string { return pr.Project.Name },
-	},
-	{
+	}},
+	{Column: teax.Column[git.PullRequest]{
 		Column:  table.Column{Title: "No.", Width: 1},
 		Extract: func(pr git.PullRequest) string { return strconv.Itoa(pr.Number) },
-	},
-	{
+		Less:    func(a, b git.PullRequest) bool { return a.Number < b.Number },
+	}},
+	{Column: teax.Column[git.PullRequest]{
 		Column:  table.Column{Title: "Title (last update: {{.LastReload.Format \"15:04:05\" }}, Δ: {{.LoadedIn.String}})", Width: 16},
 		Extract: func(pr git.PullRequest) string { return pr.Title },
-	},
-	{
+	}},
+	{Column: teax.Column[git.PullRequest]{
 		Column:  table.Column{Title: "Author", Width: 4},
 		Extract: func(pr git.PullRequest) string { return pr.Author.Username },
-	},
-	{
+	}},
+	{Column: teax.Column[git.PullRequest]{
 		Column:  table.Column{Title: "Created At", Width: 3},
 		Extract: func(pr git.PullRequest) string { return pr.CreatedAt.Format("2006-01-02") },
-	},
-	{
+		Less:    func(a, b git.PullRequest) bool { return a.CreatedAt.Before(b.CreatedAt) },
+	}},
+	{Details: engine.DetailsDiscussions | engine.DetailsCommits, Column: teax.Column[git.PullRequest]{
+		Column:  table.Column{Title: "Idle", Width: 2},
+		Extract: func(pr git.PullRequest) string { return humanizeDuration(time.Since(pr.LastActivityAt)) },
+		Less:    func(a, b git.PullRequest) bool { return a.LastActivityAt.After(b.LastActivityAt) },
+	}},
+	{Details: engine.DetailsDiscussions, Column: teax.Column[git.PullRequest]{
 		Column: table.Column{Title: "Threads", Width: 2},
 		Extract: func(pr git.PullRequest) string {
 			resolved := lo.CountBy(pr.Threads, func(t git.Comment) bool { return t.Resolved })
@@ -171,8 +392,10 @@ var ListPRColumns = []teax.Column[git.PullRequest]{
 				checkmark(resolved == len(pr.Threads)),
 			)
 		},
-	},
-	{
+		// by the number of unresolved threads
+		Less: func(a, b git.PullRequest) bool { return unresolved(a) < unresolved(b) },
+	}},
+	{Details: engine.DetailsApprovals, Column: teax.Column[git.PullRequest]{
 		Column: table.Column{Title: "Approvals", Width: 3},
 		Extract: func(pr git.PullRequest) string {
 			return fmt.Sprintf("%d/%d (%s)",
@@ -180,7 +403,56 @@ var ListPRColumns = []teax.Column[git.PullRequest]{
 				checkmark(pr.Approvals.SatisfiesRules),
 			)
 		},
-	},
+		Less: func(a, b git.PullRequest) bool { return len(a.Approvals.By) < len(b.Approvals.By) },
+	}},
+	{Column: teax.Column[git.PullRequest]{
+		Column: table.Column{Title: "Merge", Width: 2},
+		Extract: func(pr git.PullRequest) string {
+			m := pr.Mergeability
+			switch {
+			case m.HasConflicts:
+				return "conflicts (✘)"
+			case m.NeedsRebase:
+				return "rebase (✘)"
+			case m.BlockedByDiscussions:
+				return "threads (✘)"
+			default:
+				return m.Status
+			}
+		},
+	}},
+	{Details: engine.DetailsPipeline, Column: teax.Column[git.PullRequest]{
+		Column: table.Column{Title: "Pipeline", Width: 2},
+		Extract: func(pr git.PullRequest) string {
+			switch pr.Pipeline.Status {
+			case git.PipelineStatusNone:
+				return ""
+			case git.PipelineStatusSuccess, git.PipelineStatusFailed:
+				return fmt.Sprintf("%s (%s)", pr.Pipeline.Status, checkmark(pr.Pipeline.Status == git.PipelineStatusSuccess))
+			default:
+				return string(pr.Pipeline.Status)
+			}
+		},
+	}},
+}
+
+// unresolved returns the number of unresolved threads of the pull request.
+func unresolved(pr git.PullRequest) int {
+	return lo.CountBy(pr.Threads, func(t git.Comment) bool { return !t.Resolved })
+}
+
+// humanizeDuration formats the duration in the largest whole units, e.g. "3d" or "5h".
+func humanizeDuration(d time.Duration) string {
+	switch {
+	case d >= 7*24*time.Hour:
+		return fmt.Sprintf("%dw", d/(7*24*time.Hour))
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	default:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
 }
 
 func checkmark(b bool) string {
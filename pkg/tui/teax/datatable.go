@@ -3,14 +3,20 @@ package teax
 import (
 	"fmt"
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
 	"github.com/samber/lo"
 	"golang.org/x/crypto/ssh/terminal"
 	"log"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"text/template"
 	"time"
 )
@@ -20,32 +26,115 @@ import (
 type Column[T any] struct {
 	table.Column
 	Extract func(T) string
+	// Less, if set, compares the entries, when the table is sorted by the
+	// column, otherwise the extracted values are compared as strings.
+	Less func(a, b T) bool
 }
 
+// Progress reports the progress of loading: the number of loaded
+// entries and the total number of them, zero if it is not known yet.
+// It might be called concurrently.
+type Progress func(done, total int)
+
 // Actor is a data source for a table.
 type Actor[T any] interface {
-	Load() ([]T, error)
-	// OnKey is called when a key is pressed on a row.
+	// Load loads the entries, reporting the progress of loading.
+	Load(progress Progress) ([]T, error)
+	// OnKey is called when a key is pressed on a row, the message of the
+	// result, if any, is shown to the user, errors included.
 	// Note: key might be a set of keys, e.g. "ctrl+c", it is important to
 	// consider all possible combinations.
-	// It is never called on "r", "ctrl+c" or "q" key presses, as they're
-	// handled by the table itself.
-	OnKey(key string, row int, val T) (hide bool, err error)
+	// It is never called on "r", "ctrl+c", "q", "/", "esc", "s" or "S" key
+	// presses, as they're handled by the table itself.
+	OnKey(key string, row int, val T) KeyResult
+}
+
+// IncrementalActor is an Actor, that is able to load only the entries,
+// changed since the given time, so that the table merges them into the
+// loaded ones on polls. Entries might leave the data source without being
+// changed, so the table still loads all of them on demand and on each
+// fullReloadEvery'th poll.
+type IncrementalActor[T any] interface {
+	Actor[T]
+	// Key returns the unique key of the entry.
+	Key(T) string
+	// LoadChanged loads the changed entries, that satisfy the criteria of the
+	// data source, and the keys of the changed ones, that don't satisfy them anymore.
+	LoadChanged(since time.Time, progress Progress) (changed []T, removed []string, err error)
 }
 
+// fullReloadEvery is the number of polls, after which the table
+// reloads all entries of the IncrementalActor.
+const fullReloadEvery = 10
+
+// highlightMark is prepended to the first cell of the new and changed rows.
+const highlightMark = "● "
+
+// failed loads are retried after retryBackoff, doubled on each next failure
+// up to maxRetryBackoff.
+const (
+	retryBackoff    = 5 * time.Second
+	maxRetryBackoff = 5 * time.Minute
+)
+
+var (
+	statusStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	statusErrorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+)
+
 // RefreshingDataTable is a table, that loads its data from an
 // Actor with periodic updates, or on demand.
+// Entries, that appeared or changed after the first load, are
+// highlighted, until the cursor is put on them.
+// Entries are loaded in background, while the table shows the last
+// loaded ones, failed loads are retried with backoff.
+// Messages of the actor are shown in the status bar, until they expire.
+// Rows are filtered by "/", matching the query against the values of
+// columns, the filter is cleared by "esc".
+// Rows are sorted by "s", which cycles the columns, and "S", which reverses
+// the order, entries are shown in the order of the actor, until sorted.
 type RefreshingDataTable[T any] struct {
-	table table.Model
-	data  struct {
-		mu         sync.Mutex
-		entries    []T
-		lastReload time.Time
-		loadedIn   time.Duration
+	table    table.Model
+	spinner  spinner.Model
+	messages MessageBar
+	search   textinput.Model
+	data     struct {
+		mu          sync.Mutex
+		entries     []T
+		rows        []int           // indices of entries, shown in the table
+		query       []string        // terms of the search query
+		sortBy      int             // index of the column to sort by, -1 if not sorted
+		sortDesc    bool            // whether the order is descending
+		highlighted map[string]bool // keys of entries, by IncrementalActor
+		lastReload  time.Time
+		loadedIn    time.Duration
 	}
+
+	// state of loading and search, touched only by Update and View
+	polls     int // number of ticks
+	loading   bool
+	loadErr   error
+	retries   int
+	retryAt   time.Time
+	searching bool
+
+	// progress of the current load, reported by the actor
+	progress struct{ done, total atomic.Int64 }
+
 	RefreshingDataTableParams[T]
 }
 
+// loadedMsg is sent, when the entries of the target table are loaded.
+type loadedMsg[T any] struct {
+	target  any
+	full    bool
+	start   time.Time
+	entries []T // set if full
+	changed []T
+	removed []string
+	err     error
+}
+
 // RefreshingDataTableParams are the parameters to initialize a RefreshingDataTable.
 type RefreshingDataTableParams[T any] struct {
 	Columns        []Column[T]
@@ -56,7 +145,21 @@ type RefreshingDataTableParams[T any] struct {
 
 // NewRefreshingDataTable creates a new RefreshingDataTable.
 func NewRefreshingDataTable[T any](params RefreshingDataTableParams[T]) (*RefreshingDataTable[T], error) {
+	log.Printf("[DEBUG] getting terminal size")
+	width, height, err := terminal.GetSize(0)
+	if err != nil {
+		return nil, fmt.Errorf("get terminal size: %w", err)
+	}
+
+	log.Printf("[DEBUG] terminal size: %dx%d, setting to table", width, height)
+	return newRefreshingDataTable(params, width, height)
+}
+
+// newRefreshingDataTable creates a new RefreshingDataTable of the given size.
+func newRefreshingDataTable[T any](params RefreshingDataTableParams[T], width, height int) (*RefreshingDataTable[T], error) {
 	tbl := &RefreshingDataTable[T]{RefreshingDataTableParams: params}
+	tbl.data.highlighted = map[string]bool{}
+	tbl.data.sortBy = -1
 	tbl.table = table.New()
 	s := table.DefaultStyles()
 	s.Header = s.Header.
@@ -69,49 +172,112 @@ func NewRefreshingDataTable[T any](params RefreshingDataTableParams[T]) (*Refres
 		Background(lipgloss.Color("57")).
 		Bold(false)
 	tbl.table.SetStyles(s)
-
-	log.Printf("[DEBUG] getting terminal size")
-	width, height, err := terminal.GetSize(0)
-	if err != nil {
-		return nil, fmt.Errorf("get terminal size: %w", err)
-	}
-
-	log.Printf("[DEBUG] terminal size: %dx%d, setting to table", width, height)
+	tbl.spinner = spinner.New(spinner.WithSpinner(spinner.Dot))
+	tbl.search = textinput.New()
+	tbl.search.Prompt = "/"
+	tbl.search.Placeholder = "search"
 	tbl.resize(width, height)
 
-	if err = tbl.redrawColumns(); err != nil {
+	if err := tbl.redrawColumns(); err != nil {
 		return nil, fmt.Errorf("redraw columns: %w", err)
 	}
 
-	if _, err = tbl.reload(); err != nil {
-		return nil, fmt.Errorf("load for the first time: %w", err)
-	}
-
 	return tbl, nil
 }
 
 // Focus focuses the table.
 func (t *RefreshingDataTable[T]) Focus() { t.table.Focus() }
 
-// Init does nothing.
-func (t *RefreshingDataTable[T]) Init() tea.Cmd { return t.scheduleTick() }
+// Len returns the number of loaded entries.
+func (t *RefreshingDataTable[T]) Len() int {
+	t.data.mu.Lock()
+	defer t.data.mu.Unlock()
+	return len(t.data.entries)
+}
+
+// Selected returns the entry under the cursor.
+func (t *RefreshingDataTable[T]) Selected() (T, bool) { return t.entry(t.table.Cursor()) }
+
+// Searching returns true if the user is typing the search query,
+// so that all keys must go to the table.
+func (t *RefreshingDataTable[T]) Searching() bool { return t.searching }
+
+// Init starts loading the entries and schedules polls.
+func (t *RefreshingDataTable[T]) Init() tea.Cmd {
+	return tea.Batch(t.reloadCmd(true), t.scheduleTick())
+}
 
 // Update updates the table model.
 func (t *RefreshingDataTable[T]) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	log.Printf("[DEBUG][TUI-RefreshingDataTable] received message: %#v", msg)
 
-	if _, ok := msg.(tickMsg); ok {
-		return t, tea.Batch(t.reloadCmd(), t.scheduleTick())
+	if msg, ok := msg.(tickMsg); ok {
+		// there might be several tables in the program, each polls on its own
+		if msg.target != t {
+			return t, nil
+		}
+
+		t.polls++
+		return t, tea.Batch(t.reloadCmd(t.polls%fullReloadEvery == 0), t.scheduleTick())
+	}
+
+	if msg, ok := msg.(retryMsg); ok {
+		if msg.target != t {
+			return t, nil
+		}
+		return t, t.reloadCmd(msg.full)
+	}
+
+	if msg, ok := msg.(loadedMsg[T]); ok {
+		if msg.target != t {
+			return t, nil
+		}
+		return t, t.loaded(msg)
+	}
+
+	if msg, ok := msg.(messageMsg); ok {
+		if msg.target != t {
+			return t, nil
+		}
+		return t, t.messages.Post(t, msg.msg)
+	}
+
+	if msg, ok := msg.(expireMsg); ok {
+		if msg.target != t {
+			return t, nil
+		}
+		t.messages.expire(msg.seq)
+		return t, nil
+	}
+
+	if msg, ok := msg.(spinner.TickMsg); ok {
+		// spinner ignores ticks of other spinners, so only the loading table keeps spinning
+		if !t.loading {
+			return t, nil
+		}
+
+		var cmd tea.Cmd
+		t.spinner, cmd = t.spinner.Update(msg)
+		return t, cmd
 	}
 
 	if msg, ok := msg.(tea.WindowSizeMsg); ok {
 		t.resize(msg.Width, msg.Height)
 
+		// highlighted cells are truncated to the width of columns
+		t.data.mu.Lock()
+		t.setRows()
+		t.data.mu.Unlock()
+
 		log.Printf("[DEBUG][TUI-RefreshingDataTable] resizing table to new window size: %dx%d", msg.Width, msg.Height)
 		return t, tea.ClearScreen
 	}
 
 	if msg, ok := msg.(tea.KeyMsg); ok {
+		if t.searching {
+			return t, t.updateSearch(msg)
+		}
+
 		// if key is meant to be processed by the table, don't do anything
 		tblKey := []bool{
 			key.Matches(msg, table.DefaultKeyMap().LineUp),
@@ -129,6 +295,7 @@ func (t *RefreshingDataTable[T]) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if k {
 				var cmd tea.Cmd
 				t.table, cmd = t.table.Update(msg)
+				t.markSeen()
 				return t, cmd
 			}
 		}
@@ -137,16 +304,73 @@ func (t *RefreshingDataTable[T]) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "ctrl+c", "c+ctrl", "q", "й":
 			return t, tea.Quit
 		case "r", "к":
-			return t, t.reloadCmd()
+			return t, t.reloadCmd(true)
+		case "/":
+			t.searching = true
+			return t, t.search.Focus()
+		case "esc":
+			t.search.Reset()
+			t.filter()
+			return t, nil
+		case "s", "ы":
+			t.cycleSort(false)
+			return t, nil
+		case "S", "Ы":
+			t.cycleSort(true)
+			return t, nil
 		default:
 			return t, t.keyCmd(msg.String())
 		}
 	}
 
+	if t.searching {
+		// the cursor of the search input blinks
+		var cmd tea.Cmd
+		t.search, cmd = t.search.Update(msg)
+		return t, cmd
+	}
+
 	log.Printf("[DEBUG][TUI-RefreshingDataTable] unhandled message: %#v", msg)
 	return t, nil
 }
 
+// updateSearch passes the key to the search input and filters the rows by the query.
+func (t *RefreshingDataTable[T]) updateSearch(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "ctrl+c", "c+ctrl":
+		return tea.Quit
+	case "esc":
+		t.search.Reset()
+		t.filter()
+		fallthrough
+	case "enter":
+		t.searching = false
+		t.search.Blur()
+		return nil
+	}
+
+	var cmd tea.Cmd
+	t.search, cmd = t.search.Update(msg)
+	t.filter()
+	return cmd
+}
+
+// filter shows only the rows, matching the search query.
+func (t *RefreshingDataTable[T]) filter() {
+	terms := strings.Fields(t.search.Value())
+
+	t.data.mu.Lock()
+	defer t.data.mu.Unlock()
+
+	if slices.Equal(terms, t.data.query) {
+		return
+	}
+
+	t.data.query = terms
+	t.setRows()
+	t.table.SetCursor(0)
+}
+
 // View renders the table.
 func (t *RefreshingDataTable[T]) View() string {
 	t.data.mu.Lock()
@@ -155,68 +379,266 @@ func (t *RefreshingDataTable[T]) View() string {
 		log.Printf("[ERROR][TUI-RefreshingDataTable] redraw columns: %v", err)
 		return fmt.Sprintf("failed to render table: %v", err)
 	}
-	return t.table.View()
+	return lipgloss.JoinVertical(lipgloss.Left, t.table.View(),
+		lipgloss.NewStyle().MaxWidth(t.table.Width()).PaddingLeft(1).Render(
+			strings.Join(lo.Compact([]string{t.searchView(), t.messages.View(), t.statusView()}), " | "),
+		),
+	)
 }
 
-func (t *RefreshingDataTable[T]) reload() (updated bool, err error) {
+// cycleSort sorts the rows by the next column, or returns to the order of the
+// actor after the last column, or reverses the order, if reverse is set.
+// The selected entry stays under the cursor.
+func (t *RefreshingDataTable[T]) cycleSort(reverse bool) {
 	t.data.mu.Lock()
 	defer t.data.mu.Unlock()
 
-	t.data.lastReload = time.Now()
-	start := time.Now()
+	selected := -1
+	if cursor := t.table.Cursor(); cursor >= 0 && cursor < len(t.data.rows) {
+		selected = t.data.rows[cursor]
+	}
 
-	entries, err := t.Actor.Load()
-	if err != nil {
-		return false, fmt.Errorf("load entries: %w", err)
+	switch {
+	case reverse:
+		t.data.sortDesc = !t.data.sortDesc
+	case t.data.sortBy == len(t.Columns)-1:
+		t.data.sortBy = -1
+	default:
+		t.data.sortBy++
+	}
+
+	t.setRows()
+	if row := lo.IndexOf(t.data.rows, selected); row >= 0 {
+		t.table.SetCursor(row)
+	}
+}
+
+// searchView renders the search input, while the query is typed or applied.
+func (t *RefreshingDataTable[T]) searchView() string {
+	if !t.searching && t.search.Value() == "" {
+		return ""
 	}
-	t.data.entries = entries
 
-	if len(t.data.entries) > 0 {
-		t.table.SetRows(lo.Map(t.data.entries, func(entry T, _ int) table.Row {
-			return lo.Map(t.Columns, func(col Column[T], _ int) string {
-				return col.Extract(entry)
-			})
-		}))
+	view := t.search.View()
+	if len(t.data.query) > 0 {
+		view += statusStyle.Render(fmt.Sprintf(" (%d/%d, esc: clear)", len(t.data.rows), len(t.data.entries)))
 	}
-	t.data.loadedIn = time.Since(start)
+	return view
+}
+
+// statusView renders the state of loading, must be called under the lock.
+func (t *RefreshingDataTable[T]) statusView() string {
+	switch {
+	case t.loading:
+		status := t.spinner.View() + " loading"
+		if total := t.progress.total.Load(); total > 0 {
+			status += fmt.Sprintf(" %d/%d", t.progress.done.Load(), total)
+		}
+		return statusStyle.Render(status)
+	case t.loadErr != nil:
+		status := fmt.Sprintf("failed to load: %v, retrying in %s", t.loadErr, time.Until(t.retryAt).Round(time.Second))
+		if !t.data.lastReload.IsZero() {
+			status += fmt.Sprintf(", showing entries as of %s", t.data.lastReload.Format("15:04:05"))
+		}
+		return statusErrorStyle.Render(status)
+	default:
+		return statusStyle.Render(fmt.Sprintf("loaded %d entries at %s in %s",
+			len(t.data.entries), t.data.lastReload.Format("15:04:05"), t.data.loadedIn))
+	}
+}
+
+// loaded applies the loaded entries, or schedules a retry, if the load failed.
+func (t *RefreshingDataTable[T]) loaded(msg loadedMsg[T]) tea.Cmd {
+	t.loading = false
+
+	if msg.err != nil {
+		delay := retryBackoff << t.retries
+		if delay > maxRetryBackoff || delay <= 0 {
+			delay = maxRetryBackoff
+		}
+
+		log.Printf("[WARN][TUI-RefreshingDataTable] load failed, retrying in %s: %v", delay, msg.err)
+
+		t.loadErr, t.retries, t.retryAt = msg.err, t.retries+1, time.Now().Add(delay)
+		return tea.Tick(delay, func(time.Time) tea.Msg { return retryMsg{target: t, full: msg.full} })
+	}
+
+	t.loadErr, t.retries = nil, 0
+
+	t.data.mu.Lock()
+	defer t.data.mu.Unlock()
+
+	inc, incremental := t.Actor.(IncrementalActor[T])
+	firstLoad := t.data.lastReload.IsZero()
+
+	entries := msg.entries
+	switch {
+	case !msg.full:
+		log.Printf("[DEBUG][TUI-RefreshingDataTable] loaded %d changed and %d removed entries",
+			len(msg.changed), len(msg.removed))
+
+		entries = t.merge(inc, msg.changed, msg.removed)
+		for _, entry := range msg.changed {
+			t.data.highlighted[inc.Key(entry)] = true
+		}
+	case incremental && !firstLoad:
+		known := lo.SliceToMap(t.data.entries, func(entry T) (string, bool) { return inc.Key(entry), true })
+		for _, entry := range entries {
+			if !known[inc.Key(entry)] {
+				t.data.highlighted[inc.Key(entry)] = true
+			}
+		}
+	}
+
+	t.data.lastReload = msg.start
+	t.data.entries = entries
+	t.setRows()
+
+	t.data.loadedIn = time.Since(msg.start)
 	t.data.loadedIn = t.data.loadedIn.Round(100 * time.Millisecond)
 
-	return true, nil
+	return tea.ClearScreen
+}
+
+// merge replaces the changed entries, drops the removed ones
+// and puts the new ones at the top.
+func (t *RefreshingDataTable[T]) merge(inc IncrementalActor[T], changed []T, removed []string) []T {
+	drop := lo.SliceToMap(removed, func(key string) (string, bool) { return key, true })
+	updates := lo.KeyBy(changed, inc.Key)
+
+	merged := make([]T, 0, len(t.data.entries)+len(changed))
+	for _, entry := range t.data.entries {
+		key := inc.Key(entry)
+		if drop[key] {
+			delete(t.data.highlighted, key)
+			continue
+		}
+
+		if upd, ok := updates[key]; ok {
+			entry = upd
+			delete(updates, key)
+		}
+
+		merged = append(merged, entry)
+	}
+
+	added := lo.Filter(changed, func(entry T, _ int) bool {
+		_, ok := updates[inc.Key(entry)]
+		return ok
+	})
+
+	return append(added, merged...)
 }
 
-func (t *RefreshingDataTable[T]) hide(idx int) {
+// markSeen removes the highlight from the entry under the cursor.
+func (t *RefreshingDataTable[T]) markSeen() {
+	inc, ok := t.Actor.(IncrementalActor[T])
+	if !ok {
+		return
+	}
+
+	entry, ok := t.entry(t.table.Cursor())
+	if !ok {
+		return
+	}
+
 	t.data.mu.Lock()
 	defer t.data.mu.Unlock()
 
-	t.data.entries = append(t.data.entries[:idx], t.data.entries[idx+1:]...)
+	if key := inc.Key(entry); t.data.highlighted[key] {
+		delete(t.data.highlighted, key)
+		t.setRows()
+	}
+}
 
-	if len(t.data.entries) > 0 {
-		t.table.SetRows(lo.Map(t.data.entries, func(entry T, _ int) table.Row {
-			return lo.Map(t.Columns, func(col Column[T], _ int) string {
-				return col.Extract(entry)
-			})
-		}))
+// setRows renders the entries, matching the search query, into the table
+// in the sort order, must be called under the lock.
+func (t *RefreshingDataTable[T]) setRows() {
+	type row struct {
+		idx     int
+		values  []string
+		matched [][]bool
+	}
+
+	rows := make([]row, 0, len(t.data.entries))
+	for idx, entry := range t.data.entries {
+		values := lo.Map(t.Columns, func(col Column[T], _ int) string { return col.Extract(entry) })
+		if matched, ok := matchRow(values, t.data.query); ok {
+			rows = append(rows, row{idx: idx, values: values, matched: matched})
+		}
+	}
+
+	if col := t.data.sortBy; col >= 0 {
+		less := t.Columns[col].Less
+		sort.SliceStable(rows, func(i, j int) bool {
+			a, b := rows[i], rows[j]
+			if t.data.sortDesc {
+				a, b = b, a
+			}
+			if less == nil {
+				return a.values[col] < b.values[col]
+			}
+			return less(t.data.entries[a.idx], t.data.entries[b.idx])
+		})
+	}
+
+	inc, incremental := t.Actor.(IncrementalActor[T])
+	widths := t.columnWidths()
+
+	t.data.rows = make([]int, len(rows))
+	tblRows := make([]table.Row, len(rows))
+	for idx, r := range rows {
+		mark := ""
+		if incremental && t.data.highlighted[inc.Key(t.data.entries[r.idx])] {
+			mark = highlightMark
+		}
+
+		for i := range r.values {
+			if r.matched[i] != nil {
+				r.values[i] = highlight(r.values[i], r.matched[i], widths[i]-lo.Ternary(i == 0, runewidth.StringWidth(mark), 0))
+			}
+		}
+		r.values[0] = mark + r.values[0]
+
+		t.data.rows[idx] = r.idx
+		tblRows[idx] = r.values
+	}
+
+	// the table is updated even if there are no rows left, e.g. after the last
+	// one is hidden, and the cursor is kept within the rows
+	t.table.SetRows(tblRows)
+	if cursor := t.table.Cursor(); cursor < 0 || cursor >= len(tblRows) {
+		t.table.SetCursor(cursor)
 	}
 }
 
-func (t *RefreshingDataTable[T]) entry(cursor int) (v T, ok bool) {
+func (t *RefreshingDataTable[T]) hide(row int) {
 	t.data.mu.Lock()
 	defer t.data.mu.Unlock()
 
-	if len(t.data.entries) == 0 {
-		return v, false
+	if row < 0 || row >= len(t.data.rows) {
+		return
 	}
 
-	if len(t.data.entries) <= cursor || cursor < 0 {
+	idx := t.data.rows[row]
+	t.data.entries = append(t.data.entries[:idx], t.data.entries[idx+1:]...)
+	t.setRows()
+}
+
+func (t *RefreshingDataTable[T]) entry(cursor int) (v T, ok bool) {
+	t.data.mu.Lock()
+	defer t.data.mu.Unlock()
+
+	if len(t.data.rows) <= cursor || cursor < 0 {
 		return v, false
 	}
 
-	return t.data.entries[cursor], true
+	return t.data.entries[t.data.rows[cursor]], true
 }
 
 func (t *RefreshingDataTable[T]) resize(w, h int) {
 	t.table.SetWidth(w)
-	t.table.SetHeight(h - 2 - t.BorrowedHeight) // cut off the status bar and the borrowed height
+	t.table.SetHeight(h - 3 - t.BorrowedHeight) // cut off the header, the status bar and the borrowed height
 }
 
 func (t *RefreshingDataTable[T]) redrawColumns() error {
@@ -226,7 +648,7 @@ func (t *RefreshingDataTable[T]) redrawColumns() error {
 		Total      int
 	}
 
-	widthPerUnit := t.table.Width() / lo.Sum(lo.Map(t.Columns, func(c Column[T], _ int) int { return c.Width }))
+	widths := t.columnWidths()
 
 	data := columnData{LastReload: t.data.lastReload, LoadedIn: t.data.loadedIn, Total: len(t.data.entries)}
 	cols := make([]table.Column, len(t.Columns))
@@ -239,13 +661,23 @@ func (t *RefreshingDataTable[T]) redrawColumns() error {
 		if err = tmpl.Execute(buf, data); err != nil {
 			return fmt.Errorf("execute template: %w", err)
 		}
-		cols[idx] = table.Column{Title: buf.String(), Width: col.Width * widthPerUnit}
+		title := buf.String()
+		if idx == t.data.sortBy {
+			title += lo.Ternary(t.data.sortDesc, " ▼", " ▲")
+		}
+		cols[idx] = table.Column{Title: title, Width: widths[idx]}
 	}
 
 	t.table.SetColumns(cols)
 	return nil
 }
 
+// columnWidths returns the widths of columns, proportional to their units.
+func (t *RefreshingDataTable[T]) columnWidths() []int {
+	widthPerUnit := t.table.Width() / lo.Sum(lo.Map(t.Columns, func(c Column[T], _ int) int { return c.Width }))
+	return lo.Map(t.Columns, func(c Column[T], _ int) int { return c.Width * widthPerUnit })
+}
+
 func (t *RefreshingDataTable[T]) keyCmd(key string) tea.Cmd {
 	return func() tea.Msg {
 		cursor := t.table.Cursor()
@@ -256,40 +688,63 @@ func (t *RefreshingDataTable[T]) keyCmd(key string) tea.Cmd {
 			return nil
 		}
 
-		hide, err := t.Actor.OnKey(key, cursor, entry)
-		if err != nil {
-			log.Printf("[ERROR][TUI-RefreshingDataTable] OnEnter callback returned error: %v", err)
-			return tea.Quit
+		res := t.Actor.OnKey(key, cursor, entry)
+		if res.Message.Level == LevelError {
+			log.Printf("[ERROR][TUI-RefreshingDataTable] OnKey callback failed on %q: %s", key, res.Message.Text)
 		}
 
 		// we rather hide the entry instead of reloading the whole table, because reload
 		// takes time, and we don't want to block the UI for a long time
-		if hide {
+		if res.Hide {
 			log.Printf("[DEBUG][TUI-RefreshingDataTable] hiding entry at %d", cursor)
 			t.hide(cursor)
 		}
 
-		return nil
+		if res.Message.Empty() {
+			return nil
+		}
+
+		return messageMsg{target: t, msg: res.Message}
 	}
 }
 
-func (t *RefreshingDataTable[T]) reloadCmd() tea.Cmd {
-	return func() tea.Msg {
-		upd, err := t.reload()
-		if err != nil {
-			log.Printf("[ERROR][TUI-RefreshingDataTable] reload: %v", err)
-			return tea.Quit
-		}
-		if upd {
-			return tea.ClearScreen()
-		}
+// reloadCmd starts loading the entries in background, unless they're
+// being loaded already. If the actor is incremental and the reload is
+// not full, only the changed entries are loaded.
+func (t *RefreshingDataTable[T]) reloadCmd(full bool) tea.Cmd {
+	if t.loading {
 		return nil
 	}
+
+	t.data.mu.Lock()
+	since := t.data.lastReload
+	t.data.mu.Unlock()
+
+	inc, incremental := t.Actor.(IncrementalActor[T])
+	full = full || !incremental || since.IsZero()
+
+	t.loading = true
+	t.progress.done.Store(0)
+	t.progress.total.Store(0)
+	progress := func(done, total int) {
+		t.progress.done.Store(int64(done))
+		t.progress.total.Store(int64(total))
+	}
+
+	return tea.Batch(t.spinner.Tick, func() tea.Msg {
+		msg := loadedMsg[T]{target: t, full: full, start: time.Now()}
+		if full {
+			msg.entries, msg.err = t.Actor.Load(progress)
+		} else {
+			msg.changed, msg.removed, msg.err = inc.LoadChanged(since, progress)
+		}
+		return msg
+	})
 }
 
 func (t *RefreshingDataTable[T]) scheduleTick() tea.Cmd {
 	if t.PollInterval == 0 {
 		return nil
 	}
-	return tea.Tick(t.PollInterval, func(time.Time) tea.Msg { return tickMsg{} })
+	return tea.Tick(t.PollInterval, func(time.Time) tea.Msg { return tickMsg{target: t} })
 }
@@ -0,0 +1,162 @@
+package teax
+
+import (
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// stringsActor is a data source of strings, it hides the entries on "x".
+type stringsActor struct{}
+
+func (stringsActor) Load(Progress) ([]string, error) { return nil, nil }
+
+func (stringsActor) OnKey(key string, _ int, _ string) KeyResult { return KeyResult{Hide: key == "x"} }
+
+func (stringsActor) Key(s string) string { return s }
+
+func (stringsActor) LoadChanged(time.Time, Progress) ([]string, []string, error) {
+	return nil, nil, nil
+}
+
+func newTestTable(t *testing.T, entries ...string) *RefreshingDataTable[string] {
+	t.Helper()
+
+	tbl, err := newRefreshingDataTable(RefreshingDataTableParams[string]{
+		Columns: []Column[string]{{
+			Column:  table.Column{Title: "Value", Width: 1},
+			Extract: func(s string) string { return s },
+		}},
+		Actor: stringsActor{},
+	}, 80, 20)
+	require.NoError(t, err)
+
+	tbl.Focus()
+	tbl.Update(loadedMsg[string]{target: tbl, full: true, start: time.Now(), entries: entries})
+	return tbl
+}
+
+// press sends the key to the table and runs the resulting command, if it acts on the row.
+func press(tbl *RefreshingDataTable[string], key string) {
+	_, cmd := tbl.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	if cmd != nil && key == "x" {
+		cmd()
+	}
+}
+
+func TestRefreshingDataTable_emptied(t *testing.T) {
+	t.Run("all rows are hidden", func(t *testing.T) {
+		tbl := newTestTable(t, "alpha", "beta")
+
+		press(tbl, "x")
+		sel, ok := tbl.Selected()
+		require.True(t, ok)
+		assert.Equal(t, "beta", sel)
+
+		press(tbl, "x")
+		assert.Equal(t, 0, tbl.Len())
+		assert.Empty(t, tbl.table.Rows(), "table must not show the hidden rows")
+		_, ok = tbl.Selected()
+		assert.False(t, ok)
+		assert.NotContains(t, tbl.View(), "beta")
+
+		// nothing to hide anymore
+		press(tbl, "x")
+		assert.Equal(t, 0, tbl.Len())
+	})
+
+	t.Run("the last row is hidden", func(t *testing.T) {
+		tbl := newTestTable(t, "alpha", "beta", "gamma")
+		tbl.table.GotoBottom()
+
+		press(tbl, "x")
+		sel, ok := tbl.Selected()
+		require.True(t, ok, "cursor must stay within the rows")
+		assert.Equal(t, "beta", sel)
+	})
+
+	t.Run("all rows are removed by an incremental reload", func(t *testing.T) {
+		tbl := newTestTable(t, "alpha", "beta")
+
+		tbl.Update(loadedMsg[string]{target: tbl, start: time.Now(), removed: []string{"alpha", "beta"}})
+		assert.Equal(t, 0, tbl.Len())
+		assert.Empty(t, tbl.table.Rows())
+		assert.NotContains(t, tbl.View(), "alpha")
+
+		// rows appear again and the cursor is back on the first one
+		tbl.Update(loadedMsg[string]{target: tbl, start: time.Now(), changed: []string{"gamma"}})
+		sel, ok := tbl.Selected()
+		require.True(t, ok)
+		assert.Equal(t, "gamma", sel)
+	})
+}
+
+// rowEntries returns the entries in the order of the rows of the table.
+func rowEntries(tbl *RefreshingDataTable[string]) []string {
+	return lo.Map(tbl.data.rows, func(idx, _ int) string { return tbl.data.entries[idx] })
+}
+
+func TestRefreshingDataTable_cycleSort(t *testing.T) {
+	tbl, err := newRefreshingDataTable(RefreshingDataTableParams[string]{
+		Columns: []Column[string]{
+			{Column: table.Column{Title: "Value", Width: 1}, Extract: func(s string) string { return s }},
+			{
+				Column:  table.Column{Title: "Length", Width: 1},
+				Extract: func(s string) string { return strconv.Itoa(len(s)) },
+				Less:    func(a, b string) bool { return len(a) < len(b) },
+			},
+		},
+		Actor: stringsActor{},
+	}, 80, 20)
+	require.NoError(t, err)
+	tbl.Focus()
+	tbl.Update(loadedMsg[string]{target: tbl, full: true, start: time.Now(), entries: []string{"bb", "a", "cccccccccc"}})
+
+	steps := []struct {
+		key  string
+		want []string
+	}{
+		{key: "s", want: []string{"a", "bb", "cccccccccc"}}, // by value
+		{key: "S", want: []string{"cccccccccc", "bb", "a"}}, // by value, descending
+		{key: "s", want: []string{"cccccccccc", "bb", "a"}}, // by length, descending
+		{key: "S", want: []string{"a", "bb", "cccccccccc"}}, // by length with Less, not by text
+		{key: "s", want: []string{"bb", "a", "cccccccccc"}}, // unsorted
+		{key: "ы", want: []string{"a", "bb", "cccccccccc"}}, // russian layout
+	}
+
+	for _, step := range steps {
+		press(tbl, step.key)
+		assert.Equal(t, step.want, rowEntries(tbl), "after %q", step.key)
+
+		sel, ok := tbl.Selected()
+		require.True(t, ok)
+		assert.Equal(t, "bb", sel, "cursor must stay on the selected entry")
+	}
+}
+
+func TestRefreshingDataTable_search(t *testing.T) {
+	tbl := newTestTable(t, "alpha", "beta", "gamma")
+
+	press(tbl, "/")
+	require.True(t, tbl.Searching())
+	press(tbl, "am")
+	assert.Equal(t, []string{"gamma"}, rowEntries(tbl), "rows are filtered while typing")
+	assert.Contains(t, tbl.table.Rows()[0][0], highlightOn+"am"+highlightOff)
+
+	tbl.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.False(t, tbl.Searching())
+	assert.Equal(t, []string{"gamma"}, rowEntries(tbl), "query is kept after enter")
+
+	// the query is applied to the reloaded entries
+	tbl.Update(loadedMsg[string]{target: tbl, full: true, start: time.Now(), entries: []string{"alpha", "gamma", "amber"}})
+	assert.Equal(t, []string{"gamma", "amber"}, rowEntries(tbl))
+
+	tbl.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, []string{"alpha", "gamma", "amber"}, rowEntries(tbl), "esc clears the query")
+	assert.NotContains(t, tbl.table.Rows()[1][0], highlightOn)
+}
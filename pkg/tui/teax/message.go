@@ -0,0 +1,112 @@
+package teax
+
+import (
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"strings"
+	"time"
+)
+
+// Level is the severity of a message.
+type Level int
+
+// Levels of messages.
+const (
+	LevelInfo Level = iota
+	LevelWarn
+	LevelError
+)
+
+// Message is a message for the user, shown in the message bar for a while.
+type Message struct {
+	Level Level
+	Text  string
+}
+
+// Infof makes an info message.
+func Infof(format string, args ...any) Message {
+	return Message{Level: LevelInfo, Text: fmt.Sprintf(format, args...)}
+}
+
+// Warnf makes a warning message.
+func Warnf(format string, args ...any) Message {
+	return Message{Level: LevelWarn, Text: fmt.Sprintf(format, args...)}
+}
+
+// Errorf makes an error message.
+func Errorf(format string, args ...any) Message {
+	return Message{Level: LevelError, Text: fmt.Sprintf(format, args...)}
+}
+
+// Empty returns true if there is nothing to show.
+func (m Message) Empty() bool { return m.Text == "" }
+
+// ttl returns for how long the message is shown, errors stay
+// longer, so that the user has time to read them.
+func (m Message) ttl() time.Duration {
+	switch m.Level {
+	case LevelWarn:
+		return 10 * time.Second
+	case LevelError:
+		return 15 * time.Second
+	default:
+		return 5 * time.Second
+	}
+}
+
+var messageStyles = map[Level]lipgloss.Style{
+	LevelInfo:  lipgloss.NewStyle().Foreground(lipgloss.Color("10")),
+	LevelWarn:  lipgloss.NewStyle().Foreground(lipgloss.Color("11")),
+	LevelError: lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Bold(true),
+}
+
+// KeyResult is the outcome of a key press, handled by the Actor.
+type KeyResult struct {
+	Hide    bool    // hide the row from the table
+	Message Message // message to show, if not empty
+}
+
+// MessageBar shows the latest posted message, until it expires.
+type MessageBar struct {
+	msg Message
+	seq int // number of the latest message, so that only it is expired
+}
+
+// messageMsg is sent to post the message to the bar of the target model.
+type messageMsg struct {
+	target any
+	msg    Message
+}
+
+// expireMsg is sent to remove the message from the bar of the target model.
+type expireMsg struct {
+	target any
+	seq    int
+}
+
+// Post shows the message and returns the command to expire it,
+// target is the model, that receives the expiration message.
+func (b *MessageBar) Post(target any, m Message) tea.Cmd {
+	b.seq++
+	b.msg = m
+
+	seq := b.seq
+	return tea.Tick(m.ttl(), func(time.Time) tea.Msg { return expireMsg{target: target, seq: seq} })
+}
+
+// expire removes the message, unless a newer one was posted.
+func (b *MessageBar) expire(seq int) {
+	if seq == b.seq {
+		b.msg = Message{}
+	}
+}
+
+// View renders the message, or an empty string if there is none.
+func (b *MessageBar) View() string {
+	if b.msg.Empty() {
+		return ""
+	}
+	// the bar is a single line
+	return messageStyles[b.msg.Level].Render(strings.ReplaceAll(b.msg.Text, "\n", " "))
+}
@@ -0,0 +1,114 @@
+package teax
+
+import (
+	"github.com/mattn/go-runewidth"
+	"strings"
+	"unicode"
+)
+
+// highlightOn and highlightOff turn bold and underline on and off without
+// resetting other attributes, so that the highlight doesn't break the style
+// of the selected row.
+const (
+	highlightOn  = "\x1b[1;4m"
+	highlightOff = "\x1b[22;24m"
+)
+
+// matchRow matches each term of the query against the values of the row.
+// The row matches, if each term matches at least one of the values.
+// It returns the positions of the matched runes in each value.
+func matchRow(values, terms []string) (matched [][]bool, ok bool) {
+	matched = make([][]bool, len(values))
+	for _, term := range terms {
+		found := false
+		for idx, value := range values {
+			pos, ok := fuzzyMatch(value, term)
+			if !ok {
+				continue
+			}
+
+			found = true
+			if matched[idx] == nil {
+				matched[idx] = make([]bool, len([]rune(value)))
+			}
+			for _, p := range pos {
+				matched[idx][p] = true
+			}
+		}
+
+		if !found {
+			return nil, false
+		}
+	}
+
+	return matched, true
+}
+
+// fuzzyMatch case-insensitively matches the pattern against s and returns the
+// positions of the matched runes in s. The exact substring is preferred,
+// otherwise runes of the pattern must appear in s in the same order.
+func fuzzyMatch(s, pattern string) (pos []int, ok bool) {
+	src, pat := []rune(strings.ToLower(s)), []rune(strings.ToLower(pattern))
+	if len(src) != len([]rune(s)) {
+		// some runes change their length in lower case,
+		// so match them one by one to keep the positions
+		src = []rune(s)
+		for idx, r := range src {
+			src[idx] = unicode.ToLower(r)
+		}
+	}
+
+	if idx := strings.Index(string(src), string(pat)); idx >= 0 {
+		start := len([]rune(string(src)[:idx]))
+		for i := range pat {
+			pos = append(pos, start+i)
+		}
+		return pos, true
+	}
+
+	for idx, r := range src {
+		if len(pos) < len(pat) && r == pat[len(pos)] {
+			pos = append(pos, idx)
+		}
+	}
+
+	return pos, len(pos) == len(pat)
+}
+
+// highlight highlights the matched runes of s. The table truncates the cells
+// by their width, counting escape codes as visible characters, which cuts
+// them in the middle, so s is truncated here to fit the width with codes.
+func highlight(s string, matched []bool, width int) string {
+	runes := []rune(s)
+
+	render := func(n int) string {
+		sb := &strings.Builder{}
+		on := false
+		for idx, r := range runes[:n] {
+			switch {
+			case matched[idx] && !on:
+				sb.WriteString(highlightOn)
+			case !matched[idx] && on:
+				sb.WriteString(highlightOff)
+			}
+			on = matched[idx]
+			sb.WriteRune(r)
+		}
+		if on {
+			sb.WriteString(highlightOff)
+		}
+		return sb.String()
+	}
+
+	if res := render(len(runes)); runewidth.StringWidth(res) <= width {
+		return res
+	}
+
+	for n := len(runes) - 1; n > 0; n-- {
+		if res := render(n) + "…"; runewidth.StringWidth(res) <= width {
+			return res
+		}
+	}
+
+	return "…"
+}
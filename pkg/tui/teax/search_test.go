@@ -0,0 +1,109 @@
+package teax
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		pattern string
+		wantPos []int
+		wantOk  bool
+	}{
+		{name: "exact substring", s: "Hello World", pattern: "wor", wantPos: []int{6, 7, 8}, wantOk: true},
+		{name: "substring is preferred", s: "abcab", pattern: "ab", wantPos: []int{0, 1}, wantOk: true},
+		{name: "runes in order", s: "feature-branch", pattern: "fbr", wantPos: []int{0, 8, 9}, wantOk: true},
+		{name: "runes are matched greedily", s: "aXbXc", pattern: "abc", wantPos: []int{0, 2, 4}, wantOk: true},
+		{name: "runes out of order", s: "abc", pattern: "cb", wantOk: false},
+		{name: "missing rune", s: "abc", pattern: "abd", wantOk: false},
+		{name: "case of cyrillic", s: "Привет", pattern: "ВЕТ", wantPos: []int{3, 4, 5}, wantOk: true},
+		{name: "rune, longer in lower case", s: "İstanbul", pattern: "stan", wantPos: []int{1, 2, 3, 4}, wantOk: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos, ok := fuzzyMatch(tt.s, tt.pattern)
+			assert.Equal(t, tt.wantOk, ok)
+			if tt.wantOk {
+				assert.Equal(t, tt.wantPos, pos)
+			}
+		})
+	}
+}
+
+func TestMatchRow(t *testing.T) {
+	const T, F = true, false
+
+	tests := []struct {
+		name        string
+		values      []string
+		terms       []string
+		wantMatched [][]bool
+		wantOk      bool
+	}{
+		{
+			name:        "each term matches its own value",
+			values:      []string{"alice", "fix bug"},
+			terms:       []string{"ali", "bug"},
+			wantMatched: [][]bool{{T, T, T, F, F}, {F, F, F, F, T, T, T}},
+			wantOk:      true,
+		},
+		{
+			name:        "term matches several values",
+			values:      []string{"ab", "xab", "cd"},
+			terms:       []string{"ab"},
+			wantMatched: [][]bool{{T, T}, {F, T, T}, nil},
+			wantOk:      true,
+		},
+		{
+			name:        "terms match the same value",
+			values:      []string{"abcd"},
+			terms:       []string{"a", "cd"},
+			wantMatched: [][]bool{{T, F, T, T}},
+			wantOk:      true,
+		},
+		{name: "one of the terms doesn't match", values: []string{"alice", "bob"}, terms: []string{"ali", "carol"}},
+		{name: "no terms", values: []string{"alice"}, wantMatched: [][]bool{nil}, wantOk: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, ok := matchRow(tt.values, tt.terms)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.wantMatched, matched)
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	const T, F = true, false
+	on, off := highlightOn, highlightOff
+
+	tests := []struct {
+		name    string
+		s       string
+		matched []bool
+		width   int
+		want    string
+	}{
+		{name: "single rune", s: "abc", matched: []bool{F, T, F}, width: 100, want: "a" + on + "b" + off + "c"},
+		{name: "adjacent runes", s: "abcd", matched: []bool{T, T, F, T}, width: 100, want: on + "ab" + off + "c" + on + "d" + off},
+		{name: "nothing matched", s: "abc", matched: []bool{F, F, F}, width: 100, want: "abc"},
+		{name: "truncated", s: "abcdef", matched: []bool{F, F, F, F, F, F}, width: 4, want: "abc…"},
+		{
+			// codes are counted as visible characters by the table
+			name: "truncated with codes", s: "abcdef", matched: []bool{T, F, F, F, F, F}, width: 16,
+			want: on + "a" + off + "bc…",
+		},
+		{name: "too narrow", s: "abc", matched: []bool{T, F, F}, width: 0, want: "…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, highlight(tt.s, tt.matched, tt.width))
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package teax
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/samber/lo"
+	"log"
+)
+
+// Tab is a single tab of the Tabs model.
+type Tab struct {
+	// Title is called on each render, so the title may
+	// reflect the current state of the model, e.g. a counter.
+	Title func() string
+	Model tea.Model
+}
+
+// Tabs shows one of the several models at once, with the bar of
+// their titles at the top. Tabs are switched by tab/shift+tab,
+// unless the active model takes all keys, see searcher.
+type Tabs struct {
+	tabs   []Tab
+	active int
+}
+
+// searcher is implemented by models, that take all keys, while the user
+// is typing the search query, e.g. RefreshingDataTable.
+type searcher interface {
+	Searching() bool
+}
+
+// NewTabs makes a new Tabs model, the first tab is active.
+func NewTabs(tabs []Tab) *Tabs { return &Tabs{tabs: tabs} }
+
+// Active returns the index of the active tab.
+func (t *Tabs) Active() int { return t.active }
+
+// Init initializes all tabs, so that each of them is able to
+// schedule its own updates.
+func (t *Tabs) Init() tea.Cmd {
+	return tea.Batch(lo.Map(t.tabs, func(tab Tab, _ int) tea.Cmd { return tab.Model.Init() })...)
+}
+
+// Update passes key presses to the active tab only, while other
+// messages, such as ticks and window resizes, go to all tabs.
+func (t *Tabs) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		if s, ok := t.tabs[t.active].Model.(searcher); !ok || !s.Searching() {
+			switch msg.String() {
+			case "tab":
+				t.active = (t.active + 1) % len(t.tabs)
+				return t, tea.ClearScreen
+			case "shift+tab":
+				t.active = (t.active - 1 + len(t.tabs)) % len(t.tabs)
+				return t, tea.ClearScreen
+			}
+		}
+
+		var cmd tea.Cmd
+		t.tabs[t.active].Model, cmd = t.tabs[t.active].Model.Update(msg)
+		return t, cmd
+	}
+
+	log.Printf("[DEBUG][TUI-Tabs] broadcasting message: %#v", msg)
+
+	cmds := make([]tea.Cmd, len(t.tabs))
+	for idx := range t.tabs {
+		t.tabs[idx].Model, cmds[idx] = t.tabs[idx].Model.Update(msg)
+	}
+
+	return t, tea.Batch(cmds...)
+}
+
+// View renders the bar of titles and the active tab.
+func (t *Tabs) View() string {
+	titles := make([]string, len(t.tabs))
+	for idx, tab := range t.tabs {
+		style := lipgloss.NewStyle().Padding(0, 1).Foreground(lipgloss.Color("240"))
+		if idx == t.active {
+			style = style.Bold(true).
+				Foreground(lipgloss.Color("229")).
+				Background(lipgloss.Color("57"))
+		}
+		titles[idx] = style.Render(tab.Title())
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		lipgloss.NewStyle().MarginLeft(1).Render(lipgloss.JoinHorizontal(lipgloss.Top, titles...)),
+		t.tabs[t.active].Model.View())
+}
@@ -0,0 +1,38 @@
+package teax
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTabs_Update(t *testing.T) {
+	first, second := newTestTable(t, "alpha"), newTestTable(t, "beta")
+	tabs := NewTabs([]Tab{
+		{Title: func() string { return "first" }, Model: first},
+		{Title: func() string { return "second" }, Model: second},
+	})
+
+	tabs.Update(tea.KeyMsg{Type: tea.KeyTab})
+	assert.Equal(t, 1, tabs.Active())
+	tabs.Update(tea.KeyMsg{Type: tea.KeyShiftTab})
+	assert.Equal(t, 0, tabs.Active())
+
+	// keys go to the table, while it is searching
+	tabs.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	require.True(t, first.Searching())
+
+	tabs.Update(tea.KeyMsg{Type: tea.KeyTab})
+	tabs.Update(tea.KeyMsg{Type: tea.KeyShiftTab})
+	tabs.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("al")})
+	assert.Equal(t, 0, tabs.Active(), "tabs must not be switched while searching")
+	assert.Equal(t, "al", first.search.Value())
+	assert.False(t, second.Searching())
+
+	tabs.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	require.False(t, first.Searching())
+
+	tabs.Update(tea.KeyMsg{Type: tea.KeyTab})
+	assert.Equal(t, 1, tabs.Active())
+}
@@ -19,4 +19,11 @@ func Run(ctx context.Context, model tea.Model) error {
 	return nil
 }
 
-type tickMsg struct{}
+// tickMsg is sent to poll the table, which is the target.
+type tickMsg struct{ target any }
+
+// retryMsg is sent to retry the failed load of the target table.
+type retryMsg struct {
+	target any
+	full   bool
+}